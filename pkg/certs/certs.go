@@ -0,0 +1,99 @@
+// Package certs provides certificates which get reloaded from disk on changes.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/watch"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoCertificate defines the error if no certificate has been loaded yet.
+var ErrNoCertificate = errors.New("no certificate loaded")
+
+type metrics interface {
+	SetCertificateExpiry(name string, t time.Time)
+}
+
+// Provider serves a certificate pair and reloads it whenever the files change.
+type Provider struct {
+	name    string
+	cert    string
+	key     string
+	metrics metrics
+	current atomic.Pointer[tls.Certificate]
+}
+
+// NewProvider creates a provider and loads the initial certificate pair.
+func NewProvider(name, cert, key string, m metrics) (*Provider, error) {
+	p := &Provider{
+		name:    name,
+		cert:    cert,
+		key:     key,
+		metrics: m,
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetCertificate returns the current certificate, it is meant to be used by tls.Config.
+func (p *Provider) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := p.current.Load(); cert != nil {
+		return cert, nil
+	}
+
+	return nil, ErrNoCertificate
+}
+
+// Reload loads the certificate pair from disk, the previous pair is kept if it fails.
+func (p *Provider) Reload() error {
+	cert, err := tls.LoadX509KeyPair(p.cert, p.key)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate pair: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	cert.Leaf = leaf
+	p.current.Store(&cert)
+
+	if p.metrics != nil {
+		p.metrics.SetCertificateExpiry(p.name, leaf.NotAfter)
+	}
+
+	log.Info().
+		Str("name", p.name).
+		Str("cert", p.cert).
+		Str("subject", leaf.Subject.String()).
+		Time("expiry", leaf.NotAfter).
+		Msg("Loaded certificate")
+
+	return nil
+}
+
+// Watch reloads the certificate pair on file changes until the context gets canceled.
+func (p *Provider) Watch(ctx context.Context) error {
+	return watch.Watch(ctx, []string{p.cert, p.key}, func() {
+		if err := p.Reload(); err != nil {
+			log.Error().
+				Err(err).
+				Str("name", p.name).
+				Str("cert", p.cert).
+				Str("key", p.key).
+				Msg("Failed to reload certificate, keeping previous one")
+		}
+	})
+}
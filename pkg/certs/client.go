@@ -0,0 +1,180 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+const (
+	// ClientAuthNone disables the verification of client certificates.
+	ClientAuthNone = "none"
+
+	// ClientAuthOptional verifies client certificates if they are presented.
+	ClientAuthOptional = "optional"
+
+	// ClientAuthRequired requires and verifies client certificates.
+	ClientAuthRequired = "required"
+)
+
+var (
+	// ErrClientAuthMode defines the error if the client auth mode is unknown.
+	ErrClientAuthMode = errors.New("unknown client auth mode")
+
+	// ErrClientAuthCA defines the error if the client CA bundle is missing or empty.
+	ErrClientAuthCA = errors.New("no certificates found in client ca bundle")
+
+	// ErrClientNotAllowed defines the error if a client certificate is not allowlisted.
+	ErrClientNotAllowed = errors.New("client certificate not allowed")
+)
+
+// ClientPolicy defines the verification of client certificates for a listener.
+type ClientPolicy struct {
+	auth     tls.ClientAuthType
+	pool     *x509.CertPool
+	subjects []string
+	sans     []string
+}
+
+// NewClientPolicy creates a client certificate policy from the configuration.
+func NewClientPolicy(cfg config.ClientAuth) (*ClientPolicy, error) {
+	p := &ClientPolicy{
+		subjects: cfg.Subjects,
+		sans:     cfg.SANs,
+	}
+
+	switch cfg.Mode {
+	case "", ClientAuthNone:
+		p.auth = tls.NoClientCert
+
+		return p, nil
+	case ClientAuthOptional:
+		p.auth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequired:
+		p.auth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrClientAuthMode, cfg.Mode)
+	}
+
+	if cfg.CA == "" {
+		return nil, ErrClientAuthCA
+	}
+
+	content, err := os.ReadFile(cfg.CA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client ca bundle: %w", err)
+	}
+
+	p.pool = x509.NewCertPool()
+
+	if !p.pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("%w: %s", ErrClientAuthCA, cfg.CA)
+	}
+
+	return p, nil
+}
+
+// Enabled returns if client certificates get verified at all.
+func (p *ClientPolicy) Enabled() bool {
+	return p.auth != tls.NoClientCert
+}
+
+// Apply configures the client certificate verification on the TLS config.
+func (p *ClientPolicy) Apply(cfg *tls.Config) {
+	if !p.Enabled() {
+		return
+	}
+
+	cfg.ClientAuth = p.auth
+	cfg.ClientCAs = p.pool
+	cfg.VerifyConnection = p.verify
+}
+
+func (p *ClientPolicy) verify(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	if len(p.subjects) == 0 && len(p.sans) == 0 {
+		return nil
+	}
+
+	leaf := state.PeerCertificates[0]
+
+	if matchAny(p.subjects, leaf.Subject.CommonName, matchHostname) ||
+		matchAny(p.subjects, leaf.Subject.String(), matchGlob) {
+		return nil
+	}
+
+	for _, name := range leaf.DNSNames {
+		if matchAny(p.sans, name, matchHostname) {
+			return nil
+		}
+	}
+
+	for _, san := range certificateSANs(leaf) {
+		if matchAny(p.sans, san, matchGlob) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrClientNotAllowed, leaf.Subject.String())
+}
+
+// certificateSANs returns the SANs besides the DNS names, which get matched
+// as host names.
+func certificateSANs(cert *x509.Certificate) []string {
+	result := make([]string, 0, len(cert.EmailAddresses)+len(cert.IPAddresses)+len(cert.URIs))
+	result = append(result, cert.EmailAddresses...)
+
+	for _, ip := range cert.IPAddresses {
+		result = append(result, ip.String())
+	}
+
+	for _, uri := range cert.URIs {
+		result = append(result, uri.String())
+	}
+
+	return result
+}
+
+func matchAny(patterns []string, value string, match func(string, string) bool) bool {
+	for _, pattern := range patterns {
+		if pattern == value || match(pattern, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchGlob(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+
+	return err == nil && ok
+}
+
+// matchHostname compares host names case-insensitively label by label, that
+// way a * only covers a single label like the wildcards of certificates.
+func matchHostname(pattern, value string) bool {
+	patternLabels := strings.Split(strings.ToLower(pattern), ".")
+	valueLabels := strings.Split(strings.ToLower(value), ".")
+
+	if len(patternLabels) != len(valueLabels) {
+		return false
+	}
+
+	for i, label := range patternLabels {
+		if !matchGlob(label, valueLabels[i]) {
+			return false
+		}
+	}
+
+	return true
+}
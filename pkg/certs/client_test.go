@@ -0,0 +1,148 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+func clientCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "prometheus.monitoring.svc", Organization: []string{"ops"}},
+		DNSNames:       []string{"prometheus.monitoring.svc", "scraper.example.com"},
+		EmailAddresses: []string{"ops@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "cluster.local", Path: "/ns/monitoring/sa/prometheus"}},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func pemEncode(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func TestVerifyAllowlist(t *testing.T) {
+	leaf := clientCert(t)
+
+	tests := []struct {
+		name     string
+		subjects []string
+		sans     []string
+		err      error
+	}{
+		{name: "no allowlist"},
+		{name: "common name", subjects: []string{"prometheus.monitoring.svc"}},
+		{name: "common name glob", subjects: []string{"*.monitoring.svc"}},
+		{name: "distinguished name", subjects: []string{"CN=prometheus.monitoring.svc,O=ops"}},
+		{name: "dns glob", sans: []string{"*.example.com"}},
+		{name: "email", sans: []string{"ops@example.com"}},
+		{name: "ip", sans: []string{"10.0.0.1"}},
+		{name: "uri glob", sans: []string{"spiffe://cluster.local/ns/monitoring/sa/*"}},
+		{name: "subject miss", subjects: []string{"*.logging.svc"}, err: ErrClientNotAllowed},
+		{name: "glob single label", subjects: []string{"*.svc"}, err: ErrClientNotAllowed},
+		{name: "dns glob single label", sans: []string{"*.svc"}, err: ErrClientNotAllowed},
+		{name: "dns case", sans: []string{"Scraper.EXAMPLE.com"}},
+		{name: "dns glob case", sans: []string{"*.Example.COM"}},
+		{name: "common name case", subjects: []string{"PROMETHEUS.*.svc"}},
+		{name: "label glob", sans: []string{"scrap*.example.com"}},
+		{name: "glob suffix miss", subjects: []string{"*.svc.cluster.local"}, err: ErrClientNotAllowed},
+		{name: "san miss", sans: []string{"*.example.org", "10.0.0.2"}, err: ErrClientNotAllowed},
+		{name: "uri namespace miss", sans: []string{"spiffe://cluster.local/ns/logging/sa/*"}, err: ErrClientNotAllowed},
+		{name: "malformed pattern", subjects: []string{"[prometheus"}, err: ErrClientNotAllowed},
+		{name: "either list", subjects: []string{"other"}, sans: []string{"scraper.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &ClientPolicy{
+				auth:     tls.RequireAndVerifyClientCert,
+				subjects: tt.subjects,
+				sans:     tt.sans,
+			}
+
+			err := policy.verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestNewClientPolicy(t *testing.T) {
+	dir := t.TempDir()
+	leaf := clientCert(t)
+
+	ca := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca, pemEncode(leaf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("no certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		ca      string
+		enabled bool
+		err     error
+	}{
+		{name: "default"},
+		{name: "none", mode: ClientAuthNone, ca: ca},
+		{name: "optional", mode: ClientAuthOptional, ca: ca, enabled: true},
+		{name: "required", mode: ClientAuthRequired, ca: ca, enabled: true},
+		{name: "unknown mode", mode: "verify", ca: ca, err: ErrClientAuthMode},
+		{name: "missing ca", mode: ClientAuthRequired, err: ErrClientAuthCA},
+		{name: "empty ca", mode: ClientAuthRequired, ca: empty, err: ErrClientAuthCA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewClientPolicy(config.ClientAuth{Mode: tt.mode, CA: tt.ca})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if err == nil && policy.Enabled() != tt.enabled {
+				t.Fatalf("expected enabled %v", tt.enabled)
+			}
+		})
+	}
+}
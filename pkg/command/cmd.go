@@ -18,6 +18,10 @@ var (
 		SilenceUsage:  true,
 
 		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			if err := setupConfig(); err != nil {
+				return err
+			}
+
 			return setupLogger()
 		},
 
@@ -32,8 +36,6 @@ var (
 func init() {
 	cfg = config.Load()
 
-	cobra.OnInitialize(setupConfig)
-
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "Show the help, so what you see now")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "Print the current version of that tool")
 
@@ -51,6 +53,18 @@ func init() {
 	rootCmd.PersistentFlags().Bool("log-color", true, "Enable colored logging")
 	viper.SetDefault("log.color", true)
 	_ = viper.BindPFlag("log.color", rootCmd.PersistentFlags().Lookup("log-color"))
+
+	rootCmd.PersistentFlags().String("log-format", "", "Log format, json, console or logfmt, defaults to console if pretty")
+	viper.SetDefault("log.format", "")
+	_ = viper.BindPFlag("log.format", rootCmd.PersistentFlags().Lookup("log-format"))
+
+	rootCmd.PersistentFlags().Bool("log-caller", false, "Add the caller to log entries")
+	viper.SetDefault("log.caller", false)
+	_ = viper.BindPFlag("log.caller", rootCmd.PersistentFlags().Lookup("log-caller"))
+
+	rootCmd.PersistentFlags().String("log-timestamp", "rfc3339", "Timestamp format, rfc3339, rfc3339nano, unix, unixms, unixmicro, unixnano or a Go layout")
+	viper.SetDefault("log.timestamp", "rfc3339")
+	_ = viper.BindPFlag("log.timestamp", rootCmd.PersistentFlags().Lookup("log-timestamp"))
 }
 
 // Run parses the command line arguments and executes the program.
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	errorsList "github.com/owncloud-ops/errors/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+
+		// the subcommands report config errors on their own
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return nil
+		},
+	}
+
+	configCheckCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Validate the configuration and print the effective values",
+		Long: `Validate the configuration and print the effective values.
+
+The config file, environment variables and flags get merged like for the
+server, the result is printed as YAML with secrets redacted. Unknown keys
+and invalid values are reported together and result in exit code 1.`,
+		Run: configCheckAction,
+	}
+
+	configCatalogCmd = &cobra.Command{
+		Use:   "catalog",
+		Short: "Print the merged errors and the layer defining them",
+		Run:   configCatalogAction,
+	}
+
+	configSchemaCmd = &cobra.Command{
+		Use:       "schema [config|errors]",
+		Short:     "Print the JSON Schema of the config or the errors file",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"config", "errors"},
+		Run:       configSchemaAction,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configCheckCmd)
+	configCmd.AddCommand(configCatalogCmd)
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+func configCheckAction(_ *cobra.Command, _ []string) {
+	loadErr := setupConfig()
+	logErr := setupLogger()
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2) //nolint:gomnd
+
+	if err := encoder.Encode(config.Redact(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := errors.Join(loadErr, logErr, validateConfig(cfg)); err != nil {
+		fmt.Fprintf(os.Stderr, "\nConfiguration is invalid:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nConfiguration is valid")
+}
+
+func configCatalogAction(_ *cobra.Command, _ []string) {
+	if err := errors.Join(setupConfig(), setupLogger()); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	layered, err := errorsList.Resolve(cfg)
+
+	codes := make([]int, 0, len(layered))
+	for code := range layered {
+		codes = append(codes, code)
+	}
+
+	sort.Ints(codes)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintln(writer, "CODE\tLAYER\tMESSAGE")
+
+	for _, code := range codes {
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", code, layered[code].Layer, layered[code].Message)
+	}
+
+	_ = writer.Flush()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nFailed to load some layers:\n%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func configSchemaAction(_ *cobra.Command, args []string) {
+	schema := config.Schema()
+
+	if len(args) > 0 && args[0] == "errors" {
+		schema = errorsList.Schema()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(schema); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to print schema: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -2,11 +2,24 @@ package command
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
+	errorsList "github.com/owncloud-ops/errors/pkg/errors"
+	"github.com/owncloud-ops/errors/pkg/http/core"
+	"github.com/owncloud-ops/errors/pkg/listener"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,12 +28,98 @@ import (
 var healthCmd = &cobra.Command{
 	Use:   "health",
 	Short: "Perform health checks",
-	Run:   healthAction,
+	Long: `Perform health checks against the metrics or the main server.
+
+Exit codes:
+  0  target is healthy
+  1  invalid health check configuration
+  2  target could not be reached
+  3  target responded with an unexpected status
+  4  sample error page did not render as expected`,
+	Run: healthAction,
 }
 
-var exitCode = 0
+const (
+	// HealthExitOK signals a healthy target.
+	HealthExitOK = 0
+
+	// HealthExitInvalid signals an invalid health check configuration.
+	HealthExitInvalid = 1
+
+	// HealthExitUnreachable signals that the target could not be reached.
+	HealthExitUnreachable = 2
+
+	// HealthExitUnhealthy signals that the target responded with an unexpected status.
+	HealthExitUnhealthy = 3
+
+	// HealthExitRender signals that the sample error page did not render as expected.
+	HealthExitRender = 4
+)
+
+const (
+	HTTPClientTimeout = 5 * time.Second
+
+	healthTargetMetrics = "metrics"
+	healthTargetServer  = "server"
+
+	healthFormatHTML = "html"
+	healthFormatJSON = "json"
+
+	healthOutputText = "text"
+	healthOutputJSON = "json"
+)
+
+const (
+	defaultHealthTarget     = healthTargetMetrics
+	defaultHealthAddr       = ""
+	defaultHealthTLS        = false
+	defaultHealthCA         = ""
+	defaultHealthCert       = ""
+	defaultHealthKey        = ""
+	defaultHealthServerName = ""
+	defaultHealthInsecure   = false
+	defaultHealthPage       = 0
+	defaultHealthFormat     = healthFormatHTML
+	defaultHealthOutput     = healthOutputText
+)
+
+var (
+	// ErrHealthTarget defines the error if the health target is unknown.
+	ErrHealthTarget = errors.New("unknown health target")
+
+	// ErrHealthFormat defines the error if the sample page format is unknown.
+	ErrHealthFormat = errors.New("unknown sample page format")
+
+	// ErrHealthOutput defines the error if the output format is unknown.
+	ErrHealthOutput = errors.New("unknown output format")
 
-const HTTPClientTimeout = 5 * time.Second
+	// ErrHealthPage defines the error if the sample page is not a valid status code.
+	ErrHealthPage = errors.New("invalid sample page code")
+
+	// ErrHealthAddr defines the error if the address can't be probed.
+	ErrHealthAddr = errors.New("address can't be probed, use a tcp or unix address")
+
+	// ErrHealthCA defines the error if the CA bundle does not contain any certificate.
+	ErrHealthCA = errors.New("no certificates found in ca bundle")
+
+	// ErrHealthStatus defines the error if the target responds with an unexpected status.
+	ErrHealthStatus = errors.New("unexpected status code")
+
+	// ErrHealthRender defines the error if the sample page does not render as expected.
+	ErrHealthRender = errors.New("unexpected sample page")
+)
+
+type healthResult struct {
+	Target     string `json:"target"`
+	URL        string `json:"url"`
+	Status     int    `json:"status,omitempty"`
+	Page       string `json:"page,omitempty"`
+	PageStatus int    `json:"pageStatus,omitempty"`
+	Healthy    bool   `json:"healthy"`
+	ExitCode   int    `json:"exitCode"`
+	Error      string `json:"error,omitempty"`
+	Duration   string `json:"duration"`
+}
 
 func init() {
 	rootCmd.AddCommand(healthCmd)
@@ -28,47 +127,325 @@ func init() {
 	healthCmd.PersistentFlags().String("metrics-addr", defaultMetricsAddr, "Address to bind the metrics")
 	viper.SetDefault("metrics.addr", defaultMetricsAddr)
 	_ = viper.BindPFlag("metrics.addr", healthCmd.PersistentFlags().Lookup("metrics-addr"))
-}
 
-func handleExit() {
-	if exitCode != 0 {
-		os.Exit(exitCode)
-	}
+	healthCmd.PersistentFlags().String("health-target", defaultHealthTarget, "Server to probe, metrics or server")
+	viper.SetDefault("health.target", defaultHealthTarget)
+	_ = viper.BindPFlag("health.target", healthCmd.PersistentFlags().Lookup("health-target"))
+
+	healthCmd.PersistentFlags().String("health-addr", defaultHealthAddr, "Address to probe instead of the target address")
+	viper.SetDefault("health.addr", defaultHealthAddr)
+	_ = viper.BindPFlag("health.addr", healthCmd.PersistentFlags().Lookup("health-addr"))
+
+	healthCmd.PersistentFlags().Bool("health-tls", defaultHealthTLS, "Probe the target via HTTPS")
+	viper.SetDefault("health.tls", defaultHealthTLS)
+	_ = viper.BindPFlag("health.tls", healthCmd.PersistentFlags().Lookup("health-tls"))
+
+	healthCmd.PersistentFlags().String("health-ca", defaultHealthCA, "Path to CA bundle to verify the target")
+	viper.SetDefault("health.ca", defaultHealthCA)
+	_ = viper.BindPFlag("health.ca", healthCmd.PersistentFlags().Lookup("health-ca"))
+
+	healthCmd.PersistentFlags().String("health-cert", defaultHealthCert, "Path to client cert presented to the target")
+	viper.SetDefault("health.cert", defaultHealthCert)
+	_ = viper.BindPFlag("health.cert", healthCmd.PersistentFlags().Lookup("health-cert"))
+
+	healthCmd.PersistentFlags().String("health-key", defaultHealthKey, "Path to client key presented to the target")
+	viper.SetDefault("health.key", defaultHealthKey)
+	_ = viper.BindPFlag("health.key", healthCmd.PersistentFlags().Lookup("health-key"))
+
+	healthCmd.PersistentFlags().String("health-server-name", defaultHealthServerName, "Server name used for SNI and verification")
+	viper.SetDefault("health.server_name", defaultHealthServerName)
+	_ = viper.BindPFlag("health.server_name", healthCmd.PersistentFlags().Lookup("health-server-name"))
+
+	healthCmd.PersistentFlags().Bool("health-insecure", defaultHealthInsecure, "Skip verification of the target certificate")
+	viper.SetDefault("health.insecure", defaultHealthInsecure)
+	_ = viper.BindPFlag("health.insecure", healthCmd.PersistentFlags().Lookup("health-insecure"))
+
+	healthCmd.PersistentFlags().Int("health-page", defaultHealthPage, "Status code of a sample error page to verify, between 200 and 599")
+	viper.SetDefault("health.page", defaultHealthPage)
+	_ = viper.BindPFlag("health.page", healthCmd.PersistentFlags().Lookup("health-page"))
+
+	healthCmd.PersistentFlags().String("health-format", defaultHealthFormat, "Format of the sample error page, html or json")
+	viper.SetDefault("health.format", defaultHealthFormat)
+	_ = viper.BindPFlag("health.format", healthCmd.PersistentFlags().Lookup("health-format"))
+
+	healthCmd.PersistentFlags().String("health-output", defaultHealthOutput, "Output format of the result, text or json")
+	viper.SetDefault("health.output", defaultHealthOutput)
+	_ = viper.BindPFlag("health.output", healthCmd.PersistentFlags().Lookup("health-output"))
 }
 
 //nolint:revive
 func healthAction(ccmd *cobra.Command, args []string) {
-	client := http.Client{
-		Timeout: HTTPClientTimeout,
+	startedAt := time.Now()
+	result := &healthResult{
+		Target: cfg.Health.Target,
+	}
+
+	result.ExitCode, result.Error = healthCheck(result)
+	result.Healthy = result.ExitCode == HealthExitOK
+	result.Duration = time.Since(startedAt).String()
+
+	healthReport(result)
+
+	if result.ExitCode != HealthExitOK {
+		os.Exit(result.ExitCode)
+	}
+}
+
+func healthCheck(result *healthResult) (int, string) {
+	base, socket, err := healthBase()
+	if err != nil {
+		return HealthExitInvalid, err.Error()
 	}
-	url := fmt.Sprintf("http://%s/healthz", cfg.Metrics.Addr)
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	client, err := healthClient(socket)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Msg("failed to request health check")
+		return HealthExitInvalid, err.Error()
+	}
 
-		os.Exit(1)
+	result.URL = base.JoinPath("healthz").String()
+
+	status, _, _, err := healthRequest(client, result.URL, "")
+	if err != nil {
+		return HealthExitUnreachable, err.Error()
 	}
 
-	resp, err := client.Do(req)
+	result.Status = status
+
+	if status != http.StatusOK {
+		return HealthExitUnhealthy, fmt.Errorf("%w: %d", ErrHealthStatus, status).Error()
+	}
+
+	if cfg.Health.Page == 0 {
+		return HealthExitOK, ""
+	}
+
+	result.Page = base.JoinPath(strconv.Itoa(cfg.Health.Page) + ".html").String()
+
+	accept := "text/html"
+	if cfg.Health.Format == healthFormatJSON {
+		accept = "application/json"
+	}
+
+	status, contentType, body, err := healthRequest(client, result.Page, accept)
+	if err != nil {
+		return HealthExitUnreachable, err.Error()
+	}
+
+	result.PageStatus = status
+
+	if err := healthVerify(status, contentType, body); err != nil {
+		return HealthExitRender, err.Error()
+	}
+
+	return HealthExitOK, ""
+}
+
+func healthBase() (*url.URL, string, error) {
+	var (
+		addr   string
+		root   = "/"
+		secure = cfg.Health.TLS
+	)
+
+	switch cfg.Health.Target {
+	case healthTargetMetrics:
+		addr = cfg.Metrics.Addr
+		secure = secure || (cfg.Metrics.Cert != "" && cfg.Metrics.Key != "")
+	case healthTargetServer:
+		addr = cfg.Server.Addr
+		root = cfg.Server.Root
+		secure = secure || (cfg.Server.Cert != "" && cfg.Server.Key != "")
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrHealthTarget, cfg.Health.Target)
+	}
+
+	switch cfg.Health.Format {
+	case healthFormatHTML, healthFormatJSON:
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrHealthFormat, cfg.Health.Format)
+	}
+
+	switch cfg.Health.Output {
+	case healthOutputText, healthOutputJSON:
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrHealthOutput, cfg.Health.Output)
+	}
+
+	// informational codes would not be sent as final status by the server
+	if cfg.Health.Page != 0 && !core.ValidStatus(cfg.Health.Page) {
+		return nil, "", fmt.Errorf("%w: %d is not between 200 and %d", ErrHealthPage, cfg.Health.Page, errorsList.MaxCode)
+	}
+
+	if cfg.Health.Addr != "" {
+		addr = cfg.Health.Addr
+	}
+
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+
+	if socket, ok := listener.UnixPath(addr); ok {
+		return &url.URL{
+			Scheme: scheme,
+			Host:   "localhost",
+			Path:   path.Join("/", root),
+		}, socket, nil
+	}
+
+	if !listener.IsTCP(addr) {
+		return nil, "", fmt.Errorf("%w: %s", ErrHealthAddr, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	return &url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(host, port),
+		Path:   path.Join("/", root),
+	}, "", nil
+}
+
+func healthClient(socket string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+
+	if socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.Health.ServerName,
+		InsecureSkipVerify: cfg.Health.Insecure, //nolint:gosec
+	}
+
+	if cfg.Health.CA != "" {
+		content, err := os.ReadFile(cfg.Health.CA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("%w: %s", ErrHealthCA, cfg.Health.CA)
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if cfg.Health.Cert != "" && cfg.Health.Key != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Health.Cert, cfg.Health.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{
+		Timeout:   HTTPClientTimeout,
+		Transport: transport,
+	}, nil
+}
+
+func healthRequest(client *http.Client, target, accept string) (int, string, []byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, http.NoBody)
 	if err != nil {
-		log.Error().
-			Err(err).
-			Msg("failed to request health check")
+		return 0, "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		os.Exit(1)
+	if accept != "" {
+		req.Header.Set("X-Format", accept)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("failed to request target: %w", err)
 	}
 
-	defer handleExit()
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		exitCode = 42
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.StatusCode, resp.Header.Get("Content-Type"), body, nil
+}
+
+func healthVerify(status int, contentType string, body []byte) error {
+	if status != cfg.Health.Page {
+		return fmt.Errorf("%w: status %d instead of %d", ErrHealthRender, status, cfg.Health.Page)
+	}
+
+	code := strconv.Itoa(cfg.Health.Page)
+
+	switch cfg.Health.Format {
+	case healthFormatJSON:
+		if !strings.HasPrefix(contentType, "application/json") {
+			return fmt.Errorf("%w: content type %q", ErrHealthRender, contentType)
+		}
+
+		payload := struct {
+			Status string `json:"status"`
+		}{}
+
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return fmt.Errorf("%w: %w", ErrHealthRender, err)
+		}
+
+		if payload.Status != code {
+			return fmt.Errorf("%w: status %q in body", ErrHealthRender, payload.Status)
+		}
+	default:
+		if !strings.HasPrefix(contentType, "text/html") {
+			return fmt.Errorf("%w: content type %q", ErrHealthRender, contentType)
+		}
+
+		if !strings.Contains(string(body), code) {
+			return fmt.Errorf("%w: code missing in body", ErrHealthRender)
+		}
+	}
+
+	return nil
+}
+
+func healthReport(result *healthResult) {
+	if cfg.Health.Output == healthOutputJSON {
+		_ = json.NewEncoder(os.Stdout).Encode(result)
 
-		log.Error().
-			Int("code", resp.StatusCode).
-			Msg("health seems to be in bad state")
+		return
 	}
+
+	if result.Healthy {
+		log.Info().
+			Str("target", result.Target).
+			Str("url", result.URL).
+			Str("page", result.Page).
+			Str("duration", result.Duration).
+			Msg("Health check succeeded")
+
+		return
+	}
+
+	log.Error().
+		Str("target", result.Target).
+		Str("url", result.URL).
+		Str("page", result.Page).
+		Int("status", result.Status).
+		Int("code", result.ExitCode).
+		Str("error", result.Error).
+		Msg("Health check failed")
 }
@@ -4,17 +4,31 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/oklog/run"
+	"github.com/owncloud-ops/errors/pkg/certs"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/accesslog"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
 	"github.com/owncloud-ops/errors/pkg/http/router"
+	"github.com/owncloud-ops/errors/pkg/listener"
+	"github.com/owncloud-ops/errors/pkg/logger"
 	"github.com/owncloud-ops/errors/pkg/metrics"
+	"github.com/owncloud-ops/errors/pkg/snapshot"
+	"github.com/owncloud-ops/errors/pkg/tokens"
+	"github.com/owncloud-ops/errors/pkg/upstream"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var serverCmd = &cobra.Command{
@@ -24,18 +38,125 @@ var serverCmd = &cobra.Command{
 }
 
 const (
-	defaultMetricsAddr         = "0.0.0.0:8081"
-	defaultServerAddr          = "0.0.0.0:8080"
-	defaultServerPprof         = false
-	defaultServerRoot          = "/"
-	defaultServerHost          = "http://localhost:8080"
-	defaultServerCert          = ""
-	defaultServerKey           = ""
-	defaultServerStrictCurves  = false
-	defaultServerStrictCiphers = false
-	defaultServerTemplates     = ""
-	defaultServerErrors        = ""
-	defaultServerErrorsTitle   = ""
+	defaultMetricsAddr              = "0.0.0.0:8081"
+	defaultMetricsSocketMode        = "0660"
+	defaultMetricsAuth              = "bearer"
+	defaultMetricsCert              = ""
+	defaultMetricsKey               = ""
+	defaultMetricsReadTimeout       = 5 * time.Second
+	defaultMetricsReadHeaderTimeout = 0 * time.Second
+	defaultMetricsWriteTimeout      = 10 * time.Second
+	defaultMetricsIdleTimeout       = 0 * time.Second
+	defaultMetricsMaxHeaderBytes    = 1 << 20
+	defaultMetricsHandlerTimeout    = 60 * time.Second
+	defaultMetricsShutdownTimeout   = 3 * time.Second
+	defaultServerAddr               = "0.0.0.0:8080"
+	defaultServerSocketMode         = "0660"
+	defaultServerPprof              = false
+	defaultServerH2C                = false
+	defaultServerHTTP3              = false
+	defaultServerRoot               = "/"
+	defaultServerHost               = "http://localhost:8080"
+	defaultServerCert               = ""
+	defaultServerKey                = ""
+	defaultServerStrictCurves       = false
+	defaultServerStrictCiphers      = false
+	defaultServerTLSProfile         = "default"
+	defaultServerTLSMinVersion      = ""
+	defaultServerTLSMaxVersion      = ""
+	defaultServerTemplates          = ""
+	defaultServerTheme              = ""
+	defaultServerErrorsTitle        = ""
+	defaultServerFallbackCode       = 500
+	defaultServerFallbackStatus     = 0
+	defaultClientAuthMode           = "none"
+	defaultClientAuthCA             = ""
+)
+
+const (
+	defaultServerReadTimeout       = 5 * time.Second
+	defaultServerReadHeaderTimeout = 0 * time.Second
+	defaultServerWriteTimeout      = 10 * time.Second
+	defaultServerIdleTimeout       = 0 * time.Second
+	defaultServerMaxHeaderBytes    = 1 << 20
+	defaultServerHandlerTimeout    = 60 * time.Second
+	defaultServerShutdownTimeout   = 3 * time.Second
+	defaultServerDrainTimeout      = 0 * time.Second
+)
+
+//nolint:gochecknoglobals
+var (
+	defaultServerErrors = []string{}
+
+	defaultSnapshotCodes = []string{"502", "503", "504"}
+	defaultSnapshotHosts = []string{}
+	defaultSnapshotURLs  = []string{}
+
+	defaultCORSOrigins = []string{"*"}
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"authorization", "origin", "content-type", "accept"}
+
+	defaultDetailsFields        = []string{"request_id", "time", "uri", "host"}
+	defaultDetailsInternalHosts = []string{"localhost", "*.local", "*.internal", "*.svc", "*.svc.cluster.local"}
+
+	defaultAccessLogFields = []string{"ip", "method", "path", "status", "size", "duration", "request_id", "host", "uri", "format", "template"}
+)
+
+const (
+	defaultAccessLogEnabled    = false
+	defaultAccessLogOutput     = "stdout"
+	defaultAccessLogMaxSize    = 100
+	defaultAccessLogMaxBackups = 5
+	defaultAccessLogMaxAge     = 28
+	defaultAccessLogCompress   = false
+	defaultAccessLogSampling   = 1.0
+)
+
+const (
+	defaultDetailsEnabled   = false
+	defaultDetailsMaxLength = 128
+)
+
+const (
+	defaultCORSCredentials = false
+	defaultCORSMaxAge      = 0 * time.Second
+)
+
+const (
+	defaultSecurityCSP                       = "default-src 'none'; style-src 'nonce-{nonce}'; script-src 'nonce-{nonce}'; img-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+	defaultSecurityReferrerPolicy            = "no-referrer"
+	defaultSecurityPermissionsPolicy         = "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=()"
+	defaultSecurityCrossOriginOpenerPolicy   = "same-origin"
+	defaultSecurityCrossOriginEmbedderPolicy = "require-corp"
+	defaultSecurityCrossOriginResourcePolicy = "same-site"
+	defaultSecurityFrameOptions              = "DENY"
+	defaultSecurityXSSProtection             = "0"
+	defaultSecurityHSTS                      = "max-age=31536000"
+)
+
+const (
+	defaultRateLimitKey   = "none"
+	defaultRateLimitRate  = 10.0
+	defaultRateLimitBurst = 20
+	defaultRateLimitTTL   = 5 * time.Minute
+)
+
+const (
+	defaultUpstreamTimeout    = 1 * time.Second
+	defaultUpstreamTTL        = 5 * time.Minute
+	defaultUpstreamFailureTTL = 30 * time.Second
+	defaultUpstreamMaxSize    = 1 << 20
+	defaultUpstreamCSP        = "default-src 'none'; style-src 'self' https: 'nonce-{nonce}'; script-src 'nonce-{nonce}'; img-src 'self' https: data:; font-src 'self' https: data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+)
+
+const (
+	defaultSnapshotEnabled     = false
+	defaultSnapshotDir         = ""
+	defaultSnapshotMaxSize     = 100 << 20
+	defaultSnapshotMaxPageSize = 2 << 20
+	defaultSnapshotMaxAge      = 0 * time.Second
+	defaultSnapshotTimeout     = 10 * time.Second
+	defaultSnapshotCSP         = "default-src 'self' https: data:; style-src 'self' https: 'unsafe-inline'; script-src 'none'; object-src 'none'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
 )
 
 func init() {
@@ -45,18 +166,94 @@ func init() {
 	viper.SetDefault("metrics.addr", defaultMetricsAddr)
 	_ = viper.BindPFlag("metrics.addr", serverCmd.PersistentFlags().Lookup("metrics-addr"))
 
+	serverCmd.PersistentFlags().String("metrics-socket-mode", defaultMetricsSocketMode, "Permissions of the metrics unix socket")
+	viper.SetDefault("metrics.socket_mode", defaultMetricsSocketMode)
+	_ = viper.BindPFlag("metrics.socket_mode", serverCmd.PersistentFlags().Lookup("metrics-socket-mode"))
+
 	serverCmd.PersistentFlags().String("metrics-token", "", "Token to make metrics secure")
 	viper.SetDefault("metrics.token", "")
 	_ = viper.BindPFlag("metrics.token", serverCmd.PersistentFlags().Lookup("metrics-token"))
 
+	serverCmd.PersistentFlags().String("metrics-cert", defaultMetricsCert, "Path to cert for metrics SSL encryption")
+	viper.SetDefault("metrics.cert", defaultMetricsCert)
+	_ = viper.BindPFlag("metrics.cert", serverCmd.PersistentFlags().Lookup("metrics-cert"))
+
+	serverCmd.PersistentFlags().String("metrics-key", defaultMetricsKey, "Path to key for metrics SSL encryption")
+	viper.SetDefault("metrics.key", defaultMetricsKey)
+	_ = viper.BindPFlag("metrics.key", serverCmd.PersistentFlags().Lookup("metrics-key"))
+
+	serverCmd.PersistentFlags().String("metrics-client-auth", defaultClientAuthMode, "Client certificate mode for metrics, none, optional or required")
+	viper.SetDefault("metrics.client_auth.mode", defaultClientAuthMode)
+	_ = viper.BindPFlag("metrics.client_auth.mode", serverCmd.PersistentFlags().Lookup("metrics-client-auth"))
+
+	serverCmd.PersistentFlags().String("metrics-client-ca", defaultClientAuthCA, "Path to CA bundle to verify metrics clients")
+	viper.SetDefault("metrics.client_auth.ca", defaultClientAuthCA)
+	_ = viper.BindPFlag("metrics.client_auth.ca", serverCmd.PersistentFlags().Lookup("metrics-client-ca"))
+
+	serverCmd.PersistentFlags().StringSlice("metrics-client-subjects", []string{}, "Allowed subjects of metrics client certificates")
+	viper.SetDefault("metrics.client_auth.subjects", []string{})
+	_ = viper.BindPFlag("metrics.client_auth.subjects", serverCmd.PersistentFlags().Lookup("metrics-client-subjects"))
+
+	serverCmd.PersistentFlags().StringSlice("metrics-client-sans", []string{}, "Allowed SANs of metrics client certificates")
+	viper.SetDefault("metrics.client_auth.sans", []string{})
+	_ = viper.BindPFlag("metrics.client_auth.sans", serverCmd.PersistentFlags().Lookup("metrics-client-sans"))
+
+	serverCmd.PersistentFlags().StringSlice("metrics-token-files", []string{}, "Files or directories with named metrics tokens")
+	viper.SetDefault("metrics.token_files", []string{})
+	_ = viper.BindPFlag("metrics.token_files", serverCmd.PersistentFlags().Lookup("metrics-token-files"))
+
+	serverCmd.PersistentFlags().String("metrics-auth", defaultMetricsAuth, "Metrics auth mode, bearer, basic or any")
+	viper.SetDefault("metrics.auth", defaultMetricsAuth)
+	_ = viper.BindPFlag("metrics.auth", serverCmd.PersistentFlags().Lookup("metrics-auth"))
+
+	serverCmd.PersistentFlags().Duration("metrics-read-timeout", defaultMetricsReadTimeout, "Maximum duration to read a metrics request")
+	viper.SetDefault("metrics.read_timeout", defaultMetricsReadTimeout)
+	_ = viper.BindPFlag("metrics.read_timeout", serverCmd.PersistentFlags().Lookup("metrics-read-timeout"))
+
+	serverCmd.PersistentFlags().Duration("metrics-read-header-timeout", defaultMetricsReadHeaderTimeout, "Maximum duration to read metrics request headers")
+	viper.SetDefault("metrics.read_header_timeout", defaultMetricsReadHeaderTimeout)
+	_ = viper.BindPFlag("metrics.read_header_timeout", serverCmd.PersistentFlags().Lookup("metrics-read-header-timeout"))
+
+	serverCmd.PersistentFlags().Duration("metrics-write-timeout", defaultMetricsWriteTimeout, "Maximum duration to write a metrics response")
+	viper.SetDefault("metrics.write_timeout", defaultMetricsWriteTimeout)
+	_ = viper.BindPFlag("metrics.write_timeout", serverCmd.PersistentFlags().Lookup("metrics-write-timeout"))
+
+	serverCmd.PersistentFlags().Duration("metrics-idle-timeout", defaultMetricsIdleTimeout, "Maximum duration to wait for the next metrics request")
+	viper.SetDefault("metrics.idle_timeout", defaultMetricsIdleTimeout)
+	_ = viper.BindPFlag("metrics.idle_timeout", serverCmd.PersistentFlags().Lookup("metrics-idle-timeout"))
+
+	serverCmd.PersistentFlags().Int("metrics-max-header-bytes", defaultMetricsMaxHeaderBytes, "Maximum size of metrics request headers")
+	viper.SetDefault("metrics.max_header_bytes", defaultMetricsMaxHeaderBytes)
+	_ = viper.BindPFlag("metrics.max_header_bytes", serverCmd.PersistentFlags().Lookup("metrics-max-header-bytes"))
+
+	serverCmd.PersistentFlags().Duration("metrics-handler-timeout", defaultMetricsHandlerTimeout, "Maximum duration to handle a metrics request")
+	viper.SetDefault("metrics.handler_timeout", defaultMetricsHandlerTimeout)
+	_ = viper.BindPFlag("metrics.handler_timeout", serverCmd.PersistentFlags().Lookup("metrics-handler-timeout"))
+
+	serverCmd.PersistentFlags().Duration("metrics-shutdown-timeout", defaultMetricsShutdownTimeout, "Maximum duration to finish metrics requests on shutdown")
+	viper.SetDefault("metrics.shutdown_timeout", defaultMetricsShutdownTimeout)
+	_ = viper.BindPFlag("metrics.shutdown_timeout", serverCmd.PersistentFlags().Lookup("metrics-shutdown-timeout"))
+
 	serverCmd.PersistentFlags().String("server-addr", defaultServerAddr, "Address to bind the server")
 	viper.SetDefault("server.addr", defaultServerAddr)
 	_ = viper.BindPFlag("server.addr", serverCmd.PersistentFlags().Lookup("server-addr"))
 
+	serverCmd.PersistentFlags().String("server-socket-mode", defaultServerSocketMode, "Permissions of the server unix socket")
+	viper.SetDefault("server.socket_mode", defaultServerSocketMode)
+	_ = viper.BindPFlag("server.socket_mode", serverCmd.PersistentFlags().Lookup("server-socket-mode"))
+
 	serverCmd.PersistentFlags().Bool("server-pprof", defaultServerPprof, "Enable pprof debugging")
 	viper.SetDefault("server.pprof", defaultServerPprof)
 	_ = viper.BindPFlag("server.pprof", serverCmd.PersistentFlags().Lookup("server-pprof"))
 
+	serverCmd.PersistentFlags().Bool("server-h2c", defaultServerH2C, "Enable HTTP/2 cleartext for the HTTP server")
+	viper.SetDefault("server.h2c", defaultServerH2C)
+	_ = viper.BindPFlag("server.h2c", serverCmd.PersistentFlags().Lookup("server-h2c"))
+
+	serverCmd.PersistentFlags().Bool("server-http3", defaultServerHTTP3, "Enable HTTP/3 next to the HTTPS server")
+	viper.SetDefault("server.http3", defaultServerHTTP3)
+	_ = viper.BindPFlag("server.http3", serverCmd.PersistentFlags().Lookup("server-http3"))
+
 	serverCmd.PersistentFlags().String("server-root", defaultServerRoot, "Root path of the server")
 	viper.SetDefault("server.root", defaultServerRoot)
 	_ = viper.BindPFlag("server.root", serverCmd.PersistentFlags().Lookup("server-root"))
@@ -73,6 +270,22 @@ func init() {
 	viper.SetDefault("server.key", defaultServerKey)
 	_ = viper.BindPFlag("server.key", serverCmd.PersistentFlags().Lookup("server-key"))
 
+	serverCmd.PersistentFlags().String("server-client-auth", defaultClientAuthMode, "Client certificate mode, none, optional or required")
+	viper.SetDefault("server.client_auth.mode", defaultClientAuthMode)
+	_ = viper.BindPFlag("server.client_auth.mode", serverCmd.PersistentFlags().Lookup("server-client-auth"))
+
+	serverCmd.PersistentFlags().String("server-client-ca", defaultClientAuthCA, "Path to CA bundle to verify clients")
+	viper.SetDefault("server.client_auth.ca", defaultClientAuthCA)
+	_ = viper.BindPFlag("server.client_auth.ca", serverCmd.PersistentFlags().Lookup("server-client-ca"))
+
+	serverCmd.PersistentFlags().StringSlice("server-client-subjects", []string{}, "Allowed subjects of client certificates")
+	viper.SetDefault("server.client_auth.subjects", []string{})
+	_ = viper.BindPFlag("server.client_auth.subjects", serverCmd.PersistentFlags().Lookup("server-client-subjects"))
+
+	serverCmd.PersistentFlags().StringSlice("server-client-sans", []string{}, "Allowed SANs of client certificates")
+	viper.SetDefault("server.client_auth.sans", []string{})
+	_ = viper.BindPFlag("server.client_auth.sans", serverCmd.PersistentFlags().Lookup("server-client-sans"))
+
 	serverCmd.PersistentFlags().Bool("strict-curves", defaultServerStrictCurves, "Use strict SSL curves")
 	viper.SetDefault("server.strict_curves", defaultServerStrictCurves)
 	_ = viper.BindPFlag("server.strict_curves", serverCmd.PersistentFlags().Lookup("strict-curves"))
@@ -81,34 +294,443 @@ func init() {
 	viper.SetDefault("server.strict_ciphers", defaultServerStrictCiphers)
 	_ = viper.BindPFlag("server.strict_ciphers", serverCmd.PersistentFlags().Lookup("strict-ciphers"))
 
+	serverCmd.PersistentFlags().String("tls-profile", defaultServerTLSProfile, "TLS profile, default, modern, intermediate or old")
+	viper.SetDefault("server.tls_profile", defaultServerTLSProfile)
+	_ = viper.BindPFlag("server.tls_profile", serverCmd.PersistentFlags().Lookup("tls-profile"))
+
+	serverCmd.PersistentFlags().String("tls-min-version", defaultServerTLSMinVersion, "Minimal TLS version, overrides the profile")
+	viper.SetDefault("server.tls_min_version", defaultServerTLSMinVersion)
+	_ = viper.BindPFlag("server.tls_min_version", serverCmd.PersistentFlags().Lookup("tls-min-version"))
+
+	serverCmd.PersistentFlags().String("tls-max-version", defaultServerTLSMaxVersion, "Maximal TLS version, overrides the profile")
+	viper.SetDefault("server.tls_max_version", defaultServerTLSMaxVersion)
+	_ = viper.BindPFlag("server.tls_max_version", serverCmd.PersistentFlags().Lookup("tls-max-version"))
+
+	serverCmd.PersistentFlags().StringSlice("tls-curves", []string{}, "List of TLS curves, overrides the profile")
+	viper.SetDefault("server.tls_curves", []string{})
+	_ = viper.BindPFlag("server.tls_curves", serverCmd.PersistentFlags().Lookup("tls-curves"))
+
+	serverCmd.PersistentFlags().StringSlice("tls-ciphers", []string{}, "List of TLS ciphers, overrides the profile")
+	viper.SetDefault("server.tls_ciphers", []string{})
+	_ = viper.BindPFlag("server.tls_ciphers", serverCmd.PersistentFlags().Lookup("tls-ciphers"))
+
 	serverCmd.PersistentFlags().String("templates-path", defaultServerTemplates, "Path for overriding templates")
 	viper.SetDefault("server.templates", defaultServerTemplates)
 	_ = viper.BindPFlag("server.templates", serverCmd.PersistentFlags().Lookup("templates-path"))
 
-	serverCmd.PersistentFlags().String("errors-path", defaultServerErrors, "Path for overriding errors")
+	serverCmd.PersistentFlags().String("templates-theme", defaultServerTheme, "Theme of the custom templates used for hosts without a matching theme")
+	viper.SetDefault("server.theme", defaultServerTheme)
+	_ = viper.BindPFlag("server.theme", serverCmd.PersistentFlags().Lookup("templates-theme"))
+
+	serverCmd.PersistentFlags().StringSlice("errors-path", defaultServerErrors, "Paths of files or dirs with errors merged into the defaults")
 	viper.SetDefault("server.errors", defaultServerErrors)
 	_ = viper.BindPFlag("server.errors", serverCmd.PersistentFlags().Lookup("errors-path"))
 
 	serverCmd.PersistentFlags().String("errors-title", defaultServerErrorsTitle, "String for overriding errors title")
 	viper.SetDefault("server.errors_title", defaultServerErrorsTitle)
 	_ = viper.BindPFlag("server.errors_title", serverCmd.PersistentFlags().Lookup("errors-title"))
+
+	serverCmd.PersistentFlags().Int("fallback-code", defaultServerFallbackCode, "Error page rendered for unknown or invalid codes")
+	viper.SetDefault("server.fallback_code", defaultServerFallbackCode)
+	_ = viper.BindPFlag("server.fallback_code", serverCmd.PersistentFlags().Lookup("fallback-code"))
+
+	serverCmd.PersistentFlags().Int("fallback-status", defaultServerFallbackStatus, "Status sent for unknown codes, defaults to the requested code")
+	viper.SetDefault("server.fallback_status", defaultServerFallbackStatus)
+	_ = viper.BindPFlag("server.fallback_status", serverCmd.PersistentFlags().Lookup("fallback-status"))
+
+	serverCmd.PersistentFlags().Duration("server-read-timeout", defaultServerReadTimeout, "Maximum duration to read a request")
+	viper.SetDefault("server.read_timeout", defaultServerReadTimeout)
+	_ = viper.BindPFlag("server.read_timeout", serverCmd.PersistentFlags().Lookup("server-read-timeout"))
+
+	serverCmd.PersistentFlags().Duration("server-read-header-timeout", defaultServerReadHeaderTimeout, "Maximum duration to read request headers")
+	viper.SetDefault("server.read_header_timeout", defaultServerReadHeaderTimeout)
+	_ = viper.BindPFlag("server.read_header_timeout", serverCmd.PersistentFlags().Lookup("server-read-header-timeout"))
+
+	serverCmd.PersistentFlags().Duration("server-write-timeout", defaultServerWriteTimeout, "Maximum duration to write a response")
+	viper.SetDefault("server.write_timeout", defaultServerWriteTimeout)
+	_ = viper.BindPFlag("server.write_timeout", serverCmd.PersistentFlags().Lookup("server-write-timeout"))
+
+	serverCmd.PersistentFlags().Duration("server-idle-timeout", defaultServerIdleTimeout, "Maximum duration to wait for the next request")
+	viper.SetDefault("server.idle_timeout", defaultServerIdleTimeout)
+	_ = viper.BindPFlag("server.idle_timeout", serverCmd.PersistentFlags().Lookup("server-idle-timeout"))
+
+	serverCmd.PersistentFlags().Int("server-max-header-bytes", defaultServerMaxHeaderBytes, "Maximum size of request headers")
+	viper.SetDefault("server.max_header_bytes", defaultServerMaxHeaderBytes)
+	_ = viper.BindPFlag("server.max_header_bytes", serverCmd.PersistentFlags().Lookup("server-max-header-bytes"))
+
+	serverCmd.PersistentFlags().Duration("server-handler-timeout", defaultServerHandlerTimeout, "Maximum duration to handle a request")
+	viper.SetDefault("server.handler_timeout", defaultServerHandlerTimeout)
+	_ = viper.BindPFlag("server.handler_timeout", serverCmd.PersistentFlags().Lookup("server-handler-timeout"))
+
+	serverCmd.PersistentFlags().Duration("server-shutdown-timeout", defaultServerShutdownTimeout, "Maximum duration to finish requests on shutdown")
+	viper.SetDefault("server.shutdown_timeout", defaultServerShutdownTimeout)
+	_ = viper.BindPFlag("server.shutdown_timeout", serverCmd.PersistentFlags().Lookup("server-shutdown-timeout"))
+
+	serverCmd.PersistentFlags().Duration("server-drain-timeout", defaultServerDrainTimeout, "Duration to fail readiness before shutdown")
+	viper.SetDefault("server.drain_timeout", defaultServerDrainTimeout)
+	_ = viper.BindPFlag("server.drain_timeout", serverCmd.PersistentFlags().Lookup("server-drain-timeout"))
+
+	serverCmd.PersistentFlags().StringSlice("cors-origins", defaultCORSOrigins, "Allowed CORS origins, exact or glob patterns")
+	viper.SetDefault("cors.origins", defaultCORSOrigins)
+	_ = viper.BindPFlag("cors.origins", serverCmd.PersistentFlags().Lookup("cors-origins"))
+
+	serverCmd.PersistentFlags().StringSlice("cors-methods", defaultCORSMethods, "Allowed CORS methods")
+	viper.SetDefault("cors.methods", defaultCORSMethods)
+	_ = viper.BindPFlag("cors.methods", serverCmd.PersistentFlags().Lookup("cors-methods"))
+
+	serverCmd.PersistentFlags().StringSlice("cors-headers", defaultCORSHeaders, "Allowed CORS request headers")
+	viper.SetDefault("cors.headers", defaultCORSHeaders)
+	_ = viper.BindPFlag("cors.headers", serverCmd.PersistentFlags().Lookup("cors-headers"))
+
+	serverCmd.PersistentFlags().StringSlice("cors-exposed", []string{}, "CORS response headers exposed to clients")
+	viper.SetDefault("cors.exposed", []string{})
+	_ = viper.BindPFlag("cors.exposed", serverCmd.PersistentFlags().Lookup("cors-exposed"))
+
+	serverCmd.PersistentFlags().Bool("cors-credentials", defaultCORSCredentials, "Allow CORS requests with credentials")
+	viper.SetDefault("cors.credentials", defaultCORSCredentials)
+	_ = viper.BindPFlag("cors.credentials", serverCmd.PersistentFlags().Lookup("cors-credentials"))
+
+	serverCmd.PersistentFlags().Duration("cors-max-age", defaultCORSMaxAge, "Duration to cache CORS preflight results")
+	viper.SetDefault("cors.max_age", defaultCORSMaxAge)
+	_ = viper.BindPFlag("cors.max_age", serverCmd.PersistentFlags().Lookup("cors-max-age"))
+
+	serverCmd.PersistentFlags().StringSlice("cors-paths", []string{}, "Paths the CORS policy applies to, defaults to all")
+	viper.SetDefault("cors.paths", []string{})
+	_ = viper.BindPFlag("cors.paths", serverCmd.PersistentFlags().Lookup("cors-paths"))
+
+	serverCmd.PersistentFlags().String("security-csp", defaultSecurityCSP, "Content security policy, {nonce} gets replaced per request")
+	viper.SetDefault("security.csp", defaultSecurityCSP)
+	_ = viper.BindPFlag("security.csp", serverCmd.PersistentFlags().Lookup("security-csp"))
+
+	serverCmd.PersistentFlags().String("security-referrer-policy", defaultSecurityReferrerPolicy, "Value of the Referrer-Policy header")
+	viper.SetDefault("security.referrer_policy", defaultSecurityReferrerPolicy)
+	_ = viper.BindPFlag("security.referrer_policy", serverCmd.PersistentFlags().Lookup("security-referrer-policy"))
+
+	serverCmd.PersistentFlags().String("security-permissions-policy", defaultSecurityPermissionsPolicy, "Value of the Permissions-Policy header")
+	viper.SetDefault("security.permissions_policy", defaultSecurityPermissionsPolicy)
+	_ = viper.BindPFlag("security.permissions_policy", serverCmd.PersistentFlags().Lookup("security-permissions-policy"))
+
+	serverCmd.PersistentFlags().String("security-coop", defaultSecurityCrossOriginOpenerPolicy, "Value of the Cross-Origin-Opener-Policy header")
+	viper.SetDefault("security.cross_origin_opener_policy", defaultSecurityCrossOriginOpenerPolicy)
+	_ = viper.BindPFlag("security.cross_origin_opener_policy", serverCmd.PersistentFlags().Lookup("security-coop"))
+
+	serverCmd.PersistentFlags().String("security-coep", defaultSecurityCrossOriginEmbedderPolicy, "Value of the Cross-Origin-Embedder-Policy header")
+	viper.SetDefault("security.cross_origin_embedder_policy", defaultSecurityCrossOriginEmbedderPolicy)
+	_ = viper.BindPFlag("security.cross_origin_embedder_policy", serverCmd.PersistentFlags().Lookup("security-coep"))
+
+	serverCmd.PersistentFlags().String("security-corp", defaultSecurityCrossOriginResourcePolicy, "Value of the Cross-Origin-Resource-Policy header")
+	viper.SetDefault("security.cross_origin_resource_policy", defaultSecurityCrossOriginResourcePolicy)
+	_ = viper.BindPFlag("security.cross_origin_resource_policy", serverCmd.PersistentFlags().Lookup("security-corp"))
+
+	serverCmd.PersistentFlags().String("security-frame-options", defaultSecurityFrameOptions, "Value of the X-Frame-Options header")
+	viper.SetDefault("security.frame_options", defaultSecurityFrameOptions)
+	_ = viper.BindPFlag("security.frame_options", serverCmd.PersistentFlags().Lookup("security-frame-options"))
+
+	serverCmd.PersistentFlags().String("security-xss-protection", defaultSecurityXSSProtection, "Value of the legacy X-XSS-Protection header")
+	viper.SetDefault("security.xss_protection", defaultSecurityXSSProtection)
+	_ = viper.BindPFlag("security.xss_protection", serverCmd.PersistentFlags().Lookup("security-xss-protection"))
+
+	serverCmd.PersistentFlags().String("security-hsts", defaultSecurityHSTS, "Value of the Strict-Transport-Security header")
+	viper.SetDefault("security.hsts", defaultSecurityHSTS)
+	_ = viper.BindPFlag("security.hsts", serverCmd.PersistentFlags().Lookup("security-hsts"))
+
+	serverCmd.PersistentFlags().Bool("details-enabled", defaultDetailsEnabled, "Show request details on error pages")
+	viper.SetDefault("details.enabled", defaultDetailsEnabled)
+	_ = viper.BindPFlag("details.enabled", serverCmd.PersistentFlags().Lookup("details-enabled"))
+
+	serverCmd.PersistentFlags().StringSlice("details-fields", defaultDetailsFields, "Allowed request details, request_id, time, uri, host or service")
+	viper.SetDefault("details.fields", defaultDetailsFields)
+	_ = viper.BindPFlag("details.fields", serverCmd.PersistentFlags().Lookup("details-fields"))
+
+	serverCmd.PersistentFlags().Int("details-max-length", defaultDetailsMaxLength, "Maximum length of every request detail")
+	viper.SetDefault("details.max_length", defaultDetailsMaxLength)
+	_ = viper.BindPFlag("details.max_length", serverCmd.PersistentFlags().Lookup("details-max-length"))
+
+	serverCmd.PersistentFlags().StringSlice("details-internal-hosts", defaultDetailsInternalHosts, "Hosts which may show the service name, glob patterns")
+	viper.SetDefault("details.internal_hosts", defaultDetailsInternalHosts)
+	_ = viper.BindPFlag("details.internal_hosts", serverCmd.PersistentFlags().Lookup("details-internal-hosts"))
+
+	serverCmd.PersistentFlags().Bool("access-log-enabled", defaultAccessLogEnabled, "Enable the structured access log")
+	viper.SetDefault("access_log.enabled", defaultAccessLogEnabled)
+	_ = viper.BindPFlag("access_log.enabled", serverCmd.PersistentFlags().Lookup("access-log-enabled"))
+
+	serverCmd.PersistentFlags().String("access-log-output", defaultAccessLogOutput, "Access log output, stdout, stderr, file or syslog")
+	viper.SetDefault("access_log.output", defaultAccessLogOutput)
+	_ = viper.BindPFlag("access_log.output", serverCmd.PersistentFlags().Lookup("access-log-output"))
+
+	serverCmd.PersistentFlags().String("access-log-file", "", "Path to the access log file")
+	viper.SetDefault("access_log.file", "")
+	_ = viper.BindPFlag("access_log.file", serverCmd.PersistentFlags().Lookup("access-log-file"))
+
+	serverCmd.PersistentFlags().Int("access-log-max-size", defaultAccessLogMaxSize, "Size in megabytes before the access log file gets rotated")
+	viper.SetDefault("access_log.max_size", defaultAccessLogMaxSize)
+	_ = viper.BindPFlag("access_log.max_size", serverCmd.PersistentFlags().Lookup("access-log-max-size"))
+
+	serverCmd.PersistentFlags().Int("access-log-max-backups", defaultAccessLogMaxBackups, "Number of rotated access log files to keep")
+	viper.SetDefault("access_log.max_backups", defaultAccessLogMaxBackups)
+	_ = viper.BindPFlag("access_log.max_backups", serverCmd.PersistentFlags().Lookup("access-log-max-backups"))
+
+	serverCmd.PersistentFlags().Int("access-log-max-age", defaultAccessLogMaxAge, "Days to keep rotated access log files")
+	viper.SetDefault("access_log.max_age", defaultAccessLogMaxAge)
+	_ = viper.BindPFlag("access_log.max_age", serverCmd.PersistentFlags().Lookup("access-log-max-age"))
+
+	serverCmd.PersistentFlags().Bool("access-log-compress", defaultAccessLogCompress, "Compress rotated access log files")
+	viper.SetDefault("access_log.compress", defaultAccessLogCompress)
+	_ = viper.BindPFlag("access_log.compress", serverCmd.PersistentFlags().Lookup("access-log-compress"))
+
+	serverCmd.PersistentFlags().String("access-log-syslog", "", "Syslog address like udp://host:514, defaults to local syslog")
+	viper.SetDefault("access_log.syslog", "")
+	_ = viper.BindPFlag("access_log.syslog", serverCmd.PersistentFlags().Lookup("access-log-syslog"))
+
+	serverCmd.PersistentFlags().StringSlice("access-log-fields", defaultAccessLogFields, "Fields written to the access log")
+	viper.SetDefault("access_log.fields", defaultAccessLogFields)
+	_ = viper.BindPFlag("access_log.fields", serverCmd.PersistentFlags().Lookup("access-log-fields"))
+
+	serverCmd.PersistentFlags().Float64("access-log-sampling-1xx", defaultAccessLogSampling, "Ratio of logged requests with 1xx status")
+	viper.SetDefault("access_log.sampling.1xx", defaultAccessLogSampling)
+	_ = viper.BindPFlag("access_log.sampling.1xx", serverCmd.PersistentFlags().Lookup("access-log-sampling-1xx"))
+
+	serverCmd.PersistentFlags().Float64("access-log-sampling-2xx", defaultAccessLogSampling, "Ratio of logged requests with 2xx status")
+	viper.SetDefault("access_log.sampling.2xx", defaultAccessLogSampling)
+	_ = viper.BindPFlag("access_log.sampling.2xx", serverCmd.PersistentFlags().Lookup("access-log-sampling-2xx"))
+
+	serverCmd.PersistentFlags().Float64("access-log-sampling-3xx", defaultAccessLogSampling, "Ratio of logged requests with 3xx status")
+	viper.SetDefault("access_log.sampling.3xx", defaultAccessLogSampling)
+	_ = viper.BindPFlag("access_log.sampling.3xx", serverCmd.PersistentFlags().Lookup("access-log-sampling-3xx"))
+
+	serverCmd.PersistentFlags().Float64("access-log-sampling-4xx", defaultAccessLogSampling, "Ratio of logged requests with 4xx status")
+	viper.SetDefault("access_log.sampling.4xx", defaultAccessLogSampling)
+	_ = viper.BindPFlag("access_log.sampling.4xx", serverCmd.PersistentFlags().Lookup("access-log-sampling-4xx"))
+
+	serverCmd.PersistentFlags().Float64("access-log-sampling-5xx", defaultAccessLogSampling, "Ratio of logged requests with 5xx status")
+	viper.SetDefault("access_log.sampling.5xx", defaultAccessLogSampling)
+	_ = viper.BindPFlag("access_log.sampling.5xx", serverCmd.PersistentFlags().Lookup("access-log-sampling-5xx"))
+
+	serverCmd.PersistentFlags().String("rate-limit-key", defaultRateLimitKey, "Rate limit key, none, ip, host or global")
+	viper.SetDefault("rate_limit.key", defaultRateLimitKey)
+	_ = viper.BindPFlag("rate_limit.key", serverCmd.PersistentFlags().Lookup("rate-limit-key"))
+
+	serverCmd.PersistentFlags().Float64("rate-limit-rate", defaultRateLimitRate, "Allowed requests per second for each key")
+	viper.SetDefault("rate_limit.rate", defaultRateLimitRate)
+	_ = viper.BindPFlag("rate_limit.rate", serverCmd.PersistentFlags().Lookup("rate-limit-rate"))
+
+	serverCmd.PersistentFlags().Int("rate-limit-burst", defaultRateLimitBurst, "Allowed burst of requests for each key")
+	viper.SetDefault("rate_limit.burst", defaultRateLimitBurst)
+	_ = viper.BindPFlag("rate_limit.burst", serverCmd.PersistentFlags().Lookup("rate-limit-burst"))
+
+	serverCmd.PersistentFlags().Duration("rate-limit-ttl", defaultRateLimitTTL, "Duration to remember idle keys")
+	viper.SetDefault("rate_limit.ttl", defaultRateLimitTTL)
+	_ = viper.BindPFlag("rate_limit.ttl", serverCmd.PersistentFlags().Lookup("rate-limit-ttl"))
+
+	serverCmd.PersistentFlags().Duration("upstream-timeout", defaultUpstreamTimeout, "Maximum duration to fetch an upstream error page")
+	viper.SetDefault("upstream.timeout", defaultUpstreamTimeout)
+	_ = viper.BindPFlag("upstream.timeout", serverCmd.PersistentFlags().Lookup("upstream-timeout"))
+
+	serverCmd.PersistentFlags().Duration("upstream-ttl", defaultUpstreamTTL, "Duration to cache upstream error pages")
+	viper.SetDefault("upstream.ttl", defaultUpstreamTTL)
+	_ = viper.BindPFlag("upstream.ttl", serverCmd.PersistentFlags().Lookup("upstream-ttl"))
+
+	serverCmd.PersistentFlags().Duration("upstream-failure-ttl", defaultUpstreamFailureTTL, "Duration to skip an upstream after a failure")
+	viper.SetDefault("upstream.failure_ttl", defaultUpstreamFailureTTL)
+	_ = viper.BindPFlag("upstream.failure_ttl", serverCmd.PersistentFlags().Lookup("upstream-failure-ttl"))
+
+	serverCmd.PersistentFlags().Int64("upstream-max-size", defaultUpstreamMaxSize, "Maximum size of upstream error pages in bytes")
+	viper.SetDefault("upstream.max_size", defaultUpstreamMaxSize)
+	_ = viper.BindPFlag("upstream.max_size", serverCmd.PersistentFlags().Lookup("upstream-max-size"))
+
+	serverCmd.PersistentFlags().String("upstream-csp", defaultUpstreamCSP, "Content security policy of upstream error pages, {nonce} gets replaced per request")
+	viper.SetDefault("upstream.csp", defaultUpstreamCSP)
+	_ = viper.BindPFlag("upstream.csp", serverCmd.PersistentFlags().Lookup("upstream-csp"))
+
+	serverCmd.PersistentFlags().Bool("snapshot-enabled", defaultSnapshotEnabled, "Serve snapshots for failing upstreams")
+	viper.SetDefault("snapshot.enabled", defaultSnapshotEnabled)
+	_ = viper.BindPFlag("snapshot.enabled", serverCmd.PersistentFlags().Lookup("snapshot-enabled"))
+
+	serverCmd.PersistentFlags().String("snapshot-dir", defaultSnapshotDir, "Directory to store snapshots")
+	viper.SetDefault("snapshot.dir", defaultSnapshotDir)
+	_ = viper.BindPFlag("snapshot.dir", serverCmd.PersistentFlags().Lookup("snapshot-dir"))
+
+	serverCmd.PersistentFlags().StringSlice("snapshot-codes", defaultSnapshotCodes, "Codes answered by snapshots")
+	viper.SetDefault("snapshot.codes", defaultSnapshotCodes)
+	_ = viper.BindPFlag("snapshot.codes", serverCmd.PersistentFlags().Lookup("snapshot-codes"))
+
+	serverCmd.PersistentFlags().StringSlice("snapshot-hosts", defaultSnapshotHosts, "Hosts answered by snapshots, glob patterns")
+	viper.SetDefault("snapshot.hosts", defaultSnapshotHosts)
+	_ = viper.BindPFlag("snapshot.hosts", serverCmd.PersistentFlags().Lookup("snapshot-hosts"))
+
+	serverCmd.PersistentFlags().StringSlice("snapshot-urls", defaultSnapshotURLs, "URLs crawled by the snapshot command")
+	viper.SetDefault("snapshot.urls", defaultSnapshotURLs)
+	_ = viper.BindPFlag("snapshot.urls", serverCmd.PersistentFlags().Lookup("snapshot-urls"))
+
+	serverCmd.PersistentFlags().Int64("snapshot-max-size", defaultSnapshotMaxSize, "Maximum size of all snapshots in bytes")
+	viper.SetDefault("snapshot.max_size", defaultSnapshotMaxSize)
+	_ = viper.BindPFlag("snapshot.max_size", serverCmd.PersistentFlags().Lookup("snapshot-max-size"))
+
+	serverCmd.PersistentFlags().Int64("snapshot-max-page-size", defaultSnapshotMaxPageSize, "Maximum size of a single snapshot in bytes")
+	viper.SetDefault("snapshot.max_page_size", defaultSnapshotMaxPageSize)
+	_ = viper.BindPFlag("snapshot.max_page_size", serverCmd.PersistentFlags().Lookup("snapshot-max-page-size"))
+
+	serverCmd.PersistentFlags().Duration("snapshot-max-age", defaultSnapshotMaxAge, "Maximum age of served snapshots, 0 serves all")
+	viper.SetDefault("snapshot.max_age", defaultSnapshotMaxAge)
+	_ = viper.BindPFlag("snapshot.max_age", serverCmd.PersistentFlags().Lookup("snapshot-max-age"))
+
+	serverCmd.PersistentFlags().Duration("snapshot-timeout", defaultSnapshotTimeout, "Maximum duration to crawl a snapshot")
+	viper.SetDefault("snapshot.timeout", defaultSnapshotTimeout)
+	_ = viper.BindPFlag("snapshot.timeout", serverCmd.PersistentFlags().Lookup("snapshot-timeout"))
+
+	serverCmd.PersistentFlags().String("snapshot-csp", defaultSnapshotCSP, "Content security policy of snapshots, {nonce} gets replaced per request")
+	viper.SetDefault("snapshot.csp", defaultSnapshotCSP)
+	_ = viper.BindPFlag("snapshot.csp", serverCmd.PersistentFlags().Lookup("snapshot-csp"))
+
+	// the config subcommands inspect the effective server configuration including flags
+	configCheckCmd.Flags().AddFlagSet(serverCmd.PersistentFlags())
+	configCatalogCmd.Flags().AddFlagSet(serverCmd.PersistentFlags())
+	snapshotCmd.Flags().AddFlagSet(serverCmd.PersistentFlags())
 }
 
 //nolint:revive
 func serverAction(ccmd *cobra.Command, args []string) {
-	const (
-		RunTimeout       = 3 * time.Second
-		HTTPReadTimeout  = 5 * time.Second
-		HTTPWriteTimeout = 10 * time.Second
-	)
-
 	var group run.Group
 
+	if err := validateConfig(cfg); err != nil {
+		log.Error().
+			Err(err).
+			Msg("Invalid configuration")
+
+		os.Exit(1)
+	}
+
+	cfg.Metrics.Reg, cfg.Metrics.Metrics = metrics.NewRegistry(), metrics.NewMetrics()
+
+	serverClients, err := certs.NewClientPolicy(cfg.Server.ClientAuth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to load server client auth")
+
+		os.Exit(1)
+	}
+
+	metricsClients, err := certs.NewClientPolicy(cfg.Metrics.ClientAuth)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to load metrics client auth")
+
+		os.Exit(1)
+	}
+
+	static := make(map[string]string, len(cfg.Metrics.Tokens)+1)
+	for name, token := range cfg.Metrics.Tokens {
+		static[name] = token
+	}
+
+	if cfg.Metrics.Token != "" {
+		static["default"] = cfg.Metrics.Token
+	}
+
+	cfg.Metrics.Store, err = tokens.NewStore(static, cfg.Metrics.TokenFiles)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to load metrics tokens")
+
+		os.Exit(1)
+	}
+
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+
+		group.Add(func() error {
+			return cfg.Metrics.Store.Watch(ctx)
+		}, func(_ error) {
+			cancel()
+		})
+	}
+
+	if cfg.AccessLog.Enabled {
+		cfg.AccessLog.Writer, err = accesslog.Open(cfg)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Failed to open access log")
+
+			os.Exit(1)
+		}
+
+		defer func() {
+			_ = cfg.AccessLog.Writer.Close()
+		}()
+	}
+
+	if cfg.Snapshot.Enabled {
+		cfg.Snapshot.Store = snapshot.NewStore(
+			cfg.Snapshot.Dir,
+			cfg.Snapshot.MaxSize,
+			cfg.Snapshot.MaxPageSize,
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		group.Add(func() error {
+			return cfg.Snapshot.Store.Enforce(ctx, snapshot.PruneInterval)
+		}, func(_ error) {
+			cancel()
+		})
+	}
+
+	if len(cfg.Upstream.Targets) > 0 {
+		cfg.Upstream.Cache = upstream.NewCache(
+			cfg.Upstream.Timeout,
+			cfg.Upstream.TTL,
+			cfg.Upstream.FailureTTL,
+			cfg.Upstream.MaxSize,
+		)
+	}
+
+	tlsConfig, err := router.TLSConfig(cfg)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to load TLS configuration")
+
+		os.Exit(1)
+	}
+
+	if cfg.Server.H2C && cfg.Server.Cert != "" && cfg.Server.Key != "" {
+		log.Warn().
+			Msg("HTTP/2 cleartext is ignored for the HTTPS server")
+	}
+
+	if (cfg.Server.Cert != "" && cfg.Server.Key != "") || (cfg.Metrics.Cert != "" && cfg.Metrics.Key != "") {
+		logTLSConfig(tlsConfig)
+	}
+
+	serverListener, err := listener.Listen(cfg.Server.Addr, cfg.Server.SocketMode)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to listen for server")
+
+		os.Exit(1)
+	}
+
+	metricsListener, err := listener.Listen(cfg.Metrics.Addr, cfg.Metrics.SocketMode)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to listen for metrics")
+
+		os.Exit(1)
+	}
+
 	//nolint:nestif
 	if cfg.Server.Cert != "" && cfg.Server.Key != "" {
-		cert, err := tls.LoadX509KeyPair(
+		provider, err := certs.NewProvider(
+			"server",
 			cfg.Server.Cert,
 			cfg.Server.Key,
+			&cfg.Metrics.Metrics,
 		)
 		if err != nil {
 			log.Info().
@@ -119,17 +741,69 @@ func serverAction(ccmd *cobra.Command, args []string) {
 		}
 
 		server := &http.Server{
-			Addr:         cfg.Server.Addr,
-			Handler:      router.Load(cfg),
-			ReadTimeout:  HTTPReadTimeout,
-			WriteTimeout: HTTPWriteTimeout,
-			TLSConfig: &tls.Config{
-				PreferServerCipherSuites: true,
-				MinVersion:               tls.VersionTLS12,
-				CurvePreferences:         router.Curves(cfg),
-				CipherSuites:             router.Ciphers(cfg),
-				Certificates:             []tls.Certificate{cert},
-			},
+			Addr:              cfg.Server.Addr,
+			Handler:           router.Load(cfg),
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+			TLSConfig:         tlsConfig.Clone(),
+		}
+
+		server.TLSConfig.GetCertificate = provider.GetCertificate
+		serverClients.Apply(server.TLSConfig)
+
+		if cfg.Server.HTTP3 {
+			quicListener, err := net.ListenPacket("udp", cfg.Server.Addr)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Msg("Failed to listen for HTTP/3")
+
+				os.Exit(1)
+			}
+
+			h3 := &http3.Server{
+				Addr:           cfg.Server.Addr,
+				Handler:        server.Handler,
+				TLSConfig:      http3.ConfigureTLSConfig(server.TLSConfig),
+				IdleTimeout:    cfg.Server.IdleTimeout,
+				MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+			}
+
+			// the header is only set while the QUIC listener is served
+			server.Handler = header.AltSvc(h3.SetQUICHeaders)(server.Handler)
+
+			group.Add(func() error {
+				log.Info().
+					Str("addr", cfg.Server.Addr).
+					Msg("Starting HTTP/3 server")
+
+				if err := h3.Serve(quicListener); err != nil {
+					return fmt.Errorf("failed to start http3 server: %w", err)
+				}
+
+				return nil
+			}, func(reason error) {
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+				defer cancel()
+
+				// the listener was passed to the server and is not closed by it
+				defer quicListener.Close()
+
+				if err := h3.Shutdown(ctx); err != nil {
+					log.Error().
+						Err(err).
+						Msg("Failed to shutdown HTTP/3 gracefully")
+
+					return
+				}
+
+				log.Info().
+					Err(reason).
+					Msg("Shutdown HTTP/3 gracefully")
+			})
 		}
 
 		group.Add(func() error {
@@ -137,13 +811,13 @@ func serverAction(ccmd *cobra.Command, args []string) {
 				Str("addr", cfg.Server.Addr).
 				Msg("Starting HTTPS server")
 
-			if err := server.ListenAndServeTLS("", ""); err != nil {
+			if err := server.ServeTLS(serverListener, "", ""); err != nil {
 				return fmt.Errorf("failed to start https server: %w", err)
 			}
 
 			return nil
 		}, func(reason error) {
-			ctx, cancel := context.WithTimeout(context.Background(), RunTimeout)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 			defer cancel()
 
 			if err := server.Shutdown(ctx); err != nil {
@@ -158,12 +832,27 @@ func serverAction(ccmd *cobra.Command, args []string) {
 				Err(reason).
 				Msg("Shutdown HTTPS gracefully")
 		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		group.Add(func() error {
+			return provider.Watch(ctx)
+		}, func(_ error) {
+			cancel()
+		})
 	} else {
 		server := &http.Server{
-			Addr:         cfg.Server.Addr,
-			Handler:      router.Load(cfg),
-			ReadTimeout:  HTTPReadTimeout,
-			WriteTimeout: HTTPWriteTimeout,
+			Addr:              cfg.Server.Addr,
+			Handler:           router.Load(cfg),
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+			MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		}
+
+		if cfg.Server.H2C {
+			server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
 		}
 
 		group.Add(func() error {
@@ -171,13 +860,13 @@ func serverAction(ccmd *cobra.Command, args []string) {
 				Str("addr", cfg.Server.Addr).
 				Msg("Starting HTTP server")
 
-			if err := server.ListenAndServe(); err != nil {
+			if err := server.Serve(serverListener); err != nil {
 				return fmt.Errorf("failed to start http server: %w", err)
 			}
 
 			return nil
 		}, func(reason error) {
-			ctx, cancel := context.WithTimeout(context.Background(), RunTimeout)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 			defer cancel()
 
 			if err := server.Shutdown(ctx); err != nil {
@@ -194,13 +883,83 @@ func serverAction(ccmd *cobra.Command, args []string) {
 		})
 	}
 
-	{
-		cfg.Metrics.Reg, cfg.Metrics.Metrics = metrics.NewRegistry(), metrics.NewMetrics()
+	//nolint:nestif
+	if cfg.Metrics.Cert != "" && cfg.Metrics.Key != "" {
+		provider, err := certs.NewProvider(
+			"metrics",
+			cfg.Metrics.Cert,
+			cfg.Metrics.Key,
+			&cfg.Metrics.Metrics,
+		)
+		if err != nil {
+			log.Info().
+				Err(err).
+				Msg("Failed to load metrics certificates")
+
+			os.Exit(1)
+		}
+
 		server := &http.Server{
-			Addr:         cfg.Metrics.Addr,
-			Handler:      router.Metrics(cfg),
-			ReadTimeout:  HTTPReadTimeout,
-			WriteTimeout: HTTPWriteTimeout,
+			Addr:              cfg.Metrics.Addr,
+			Handler:           router.Metrics(cfg),
+			ReadTimeout:       cfg.Metrics.ReadTimeout,
+			ReadHeaderTimeout: cfg.Metrics.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Metrics.WriteTimeout,
+			IdleTimeout:       cfg.Metrics.IdleTimeout,
+			MaxHeaderBytes:    cfg.Metrics.MaxHeaderBytes,
+			TLSConfig:         tlsConfig.Clone(),
+		}
+
+		server.TLSConfig.GetCertificate = provider.GetCertificate
+		metricsClients.Apply(server.TLSConfig)
+
+		group.Add(func() error {
+			log.Info().
+				Str("addr", cfg.Metrics.Addr).
+				Msg("Starting HTTPS metrics server")
+
+			if err := cfg.Metrics.Metrics.Register(cfg.Metrics.Reg); err != nil {
+				return fmt.Errorf("failed register metrics: %w", err)
+			}
+
+			if err := server.ServeTLS(metricsListener, "", ""); err != nil {
+				return fmt.Errorf("failed to start https metrics server: %w", err)
+			}
+
+			return nil
+		}, func(reason error) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Metrics.ShutdownTimeout)
+			defer cancel()
+
+			if err := server.Shutdown(ctx); err != nil {
+				log.Error().
+					Err(err).
+					Msg("Failed to shutdown HTTPS metrics gracefully")
+
+				return
+			}
+
+			log.Info().
+				Err(reason).
+				Msg("Shutdown HTTPS metrics gracefully")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		group.Add(func() error {
+			return provider.Watch(ctx)
+		}, func(_ error) {
+			cancel()
+		})
+	} else {
+		server := &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           router.Metrics(cfg),
+			ReadTimeout:       cfg.Metrics.ReadTimeout,
+			ReadHeaderTimeout: cfg.Metrics.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Metrics.WriteTimeout,
+			IdleTimeout:       cfg.Metrics.IdleTimeout,
+			MaxHeaderBytes:    cfg.Metrics.MaxHeaderBytes,
 		}
 
 		group.Add(func() error {
@@ -212,13 +971,13 @@ func serverAction(ccmd *cobra.Command, args []string) {
 				return fmt.Errorf("failed register metrics: %w", err)
 			}
 
-			if err := server.ListenAndServe(); err != nil {
+			if err := server.Serve(metricsListener); err != nil {
 				return fmt.Errorf("failed to start metrics server: %w", err)
 			}
 
 			return nil
 		}, func(reason error) {
-			ctx, cancel := context.WithTimeout(context.Background(), RunTimeout)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Metrics.ShutdownTimeout)
 			defer cancel()
 
 			if err := server.Shutdown(ctx); err != nil {
@@ -239,17 +998,90 @@ func serverAction(ccmd *cobra.Command, args []string) {
 		stop := make(chan os.Signal, 1)
 
 		group.Add(func() error {
-			signal.Notify(stop, os.Interrupt)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+			sig, ok := <-stop
+			if !ok || cfg.Server.DrainTimeout <= 0 {
+				return nil
+			}
 
-			<-stop
+			cfg.Server.Draining.Store(true)
+
+			log.Info().
+				Str("signal", sig.String()).
+				Dur("drain", cfg.Server.DrainTimeout).
+				Msg("Draining before shutdown")
+
+			select {
+			case <-time.After(cfg.Server.DrainTimeout):
+			case <-stop:
+			}
 
 			return nil
 		}, func(err error) {
+			signal.Stop(stop)
 			close(stop)
 		})
 	}
 
+	{
+		signals := make(chan os.Signal, 1)
+
+		group.Add(func() error {
+			signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+			for sig := range signals {
+				steps := 1
+
+				if sig == syscall.SIGUSR1 {
+					steps = -1
+				}
+
+				log.WithLevel(zerolog.NoLevel).
+					Str("signal", sig.String()).
+					Str("log_level", logger.Step(steps).String()).
+					Msg("Changed log level")
+			}
+
+			return nil
+		}, func(_ error) {
+			signal.Stop(signals)
+			close(signals)
+		})
+	}
+
 	if err := group.Run(); err != nil {
 		os.Exit(1)
 	}
 }
+
+func logTLSConfig(tlsConfig *tls.Config) {
+	maxVersion := "auto"
+	if tlsConfig.MaxVersion != 0 {
+		maxVersion = tls.VersionName(tlsConfig.MaxVersion)
+	}
+
+	curves := make([]string, 0, len(tlsConfig.CurvePreferences))
+	for _, curve := range tlsConfig.CurvePreferences {
+		curves = append(curves, curve.String())
+	}
+
+	ciphers := make([]string, 0, len(tlsConfig.CipherSuites))
+	for _, cipher := range tlsConfig.CipherSuites {
+		ciphers = append(ciphers, tls.CipherSuiteName(cipher))
+	}
+
+	log.Info().
+		Str("profile", cfg.Server.TLSProfile).
+		Str("min", tls.VersionName(tlsConfig.MinVersion)).
+		Str("max", maxVersion).
+		Strs("curves", curves).
+		Strs("ciphers", ciphers).
+		Msg("Effective TLS policy")
+
+	if insecure := router.InsecureCiphers(tlsConfig); len(insecure) > 0 {
+		log.Warn().
+			Strs("ciphers", insecure).
+			Msg("Insecure TLS ciphers enabled")
+	}
+}
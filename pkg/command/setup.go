@@ -2,45 +2,53 @@ package command
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/logger"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
 func setupLogger() error {
-	switch strings.ToLower(viper.GetString("log.level")) {
-	case "panic":
-		zerolog.SetGlobalLevel(zerolog.PanicLevel)
-	case "fatal":
-		zerolog.SetGlobalLevel(zerolog.FatalLevel)
-	case "error":
-		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
-	case "warn":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
-	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	default:
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	level, err := logger.ParseLevel(viper.GetString("log.level"))
+	if err != nil {
+		return err
 	}
 
-	if viper.GetBool("log.pretty") {
-		log.Logger = log.Output(
-			zerolog.ConsoleWriter{
-				Out:     os.Stderr,
-				NoColor: !viper.GetBool("log.color"),
-			},
-		)
+	zerolog.SetGlobalLevel(level)
+	zerolog.TimeFieldFormat = logger.TimeFormat(viper.GetString("log.timestamp"))
+
+	format := viper.GetString("log.format")
+
+	if format == "" {
+		format = logger.FormatJSON
+
+		if viper.GetBool("log.pretty") {
+			format = logger.FormatConsole
+		}
+	}
+
+	writer, err := logger.Writer(os.Stderr, format, viper.GetBool("log.color"))
+	if err != nil {
+		return err
+	}
+
+	log.Logger = log.Output(writer)
+
+	if viper.GetBool("log.caller") {
+		log.Logger = log.Logger.With().Caller().Logger()
 	}
 
 	return nil
 }
 
-func setupConfig() {
+// setupConfig reads the config file and environment into the configuration,
+// read and parse errors as well as unknown keys are aggregated.
+func setupConfig() error {
 	viper.SetEnvPrefix("errors")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
@@ -54,18 +62,27 @@ func setupConfig() {
 		viper.AddConfigPath("./errors")
 	}
 
+	var errs []error
+
 	var errConfigFileNotFound viper.ConfigFileNotFoundError
 	if err := viper.ReadInConfig(); err != nil {
 		if ok := errors.As(err, &errConfigFileNotFound); !ok {
-			log.Error().
-				Err(err).
-				Msg("Failed to read config file")
+			errs = append(errs, fmt.Errorf("failed to read config file: %w", err))
+		}
+	} else {
+		file := viper.New()
+		file.SetConfigFile(viper.ConfigFileUsed())
+
+		if err := file.ReadInConfig(); err == nil {
+			for _, key := range config.UnknownKeys(file.AllSettings()) {
+				errs = append(errs, fmt.Errorf("%w: %s", config.ErrUnknownKey, key))
+			}
 		}
 	}
 
 	if err := viper.Unmarshal(cfg); err != nil {
-		log.Error().
-			Err(err).
-			Msg("Failed to parse config file")
+		errs = append(errs, fmt.Errorf("failed to parse config file: %w", err))
 	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/owncloud-ops/errors/pkg/http/core"
+	"github.com/owncloud-ops/errors/pkg/snapshot"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// ErrNoSnapshotURLs defines the error if there is nothing to crawl.
+var ErrNoSnapshotURLs = errors.New("no snapshot urls configured")
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [url...]",
+	Short: "Crawl pages and store snapshots for failing upstreams",
+	Long: `Crawl pages and store snapshots for failing upstreams.
+
+The configured snapshot URLs or the URLs passed as arguments get fetched and
+stored within the snapshot directory, afterwards the oldest snapshots are
+pruned until the maximum size fits. Failed pages keep their previous snapshot
+and result in exit code 1.`,
+	Run: snapshotAction,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func snapshotAction(_ *cobra.Command, args []string) {
+	urls := cfg.Snapshot.URLs
+
+	if len(args) > 0 {
+		urls = args
+	}
+
+	if err := validateSnapshot(urls); err != nil {
+		log.Error().
+			Err(err).
+			Msg("Invalid snapshot configuration")
+
+		os.Exit(1)
+	}
+
+	store := snapshot.NewStore(cfg.Snapshot.Dir, cfg.Snapshot.MaxSize, cfg.Snapshot.MaxPageSize)
+	client := &http.Client{
+		Timeout: cfg.Snapshot.Timeout,
+	}
+
+	failed := 0
+
+	for _, url := range urls {
+		page, err := store.Crawl(context.Background(), client, url)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("url", url).
+				Msg("Failed to store snapshot")
+
+			failed++
+
+			continue
+		}
+
+		log.Info().
+			Str("url", url).
+			Int64("size", page.Size).
+			Msg("Stored snapshot")
+	}
+
+	removed, err := store.Prune()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to prune snapshots")
+
+		os.Exit(1)
+	}
+
+	if removed > 0 {
+		log.Info().
+			Int("removed", removed).
+			Msg("Pruned oldest snapshots")
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// validateSnapshot checks the snapshot configuration and the URLs to crawl.
+func validateSnapshot(urls []string) error {
+	errs := []error{
+		core.ValidateSnapshot(cfg),
+	}
+
+	if len(urls) == 0 {
+		errs = append(errs, ErrNoSnapshotURLs)
+	}
+
+	if cfg.Snapshot.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("%w: timeout must be positive", core.ErrSnapshot))
+	}
+
+	for _, url := range urls {
+		if _, err := snapshot.ParseURL(url); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/certs"
+	"github.com/owncloud-ops/errors/pkg/config"
+	errorsList "github.com/owncloud-ops/errors/pkg/errors"
+	"github.com/owncloud-ops/errors/pkg/http/core"
+	metricsHandler "github.com/owncloud-ops/errors/pkg/http/handler/metrics"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/accesslog"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/cors"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/ratelimit"
+	"github.com/owncloud-ops/errors/pkg/http/router"
+	"github.com/owncloud-ops/errors/pkg/listener"
+)
+
+// ErrInvalidConfig defines the error if a config value is invalid.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// validateConfig checks the whole server configuration and aggregates all errors.
+func validateConfig(cfg *config.Config) error {
+	var errs []error
+
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	invalid := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %w: %s", key, ErrInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+
+	check("server.addr", listener.Validate(cfg.Server.Addr, cfg.Server.SocketMode))
+	check("metrics.addr", listener.Validate(cfg.Metrics.Addr, cfg.Metrics.SocketMode))
+
+	if !strings.HasPrefix(cfg.Server.Root, "/") {
+		invalid("server.root", "must start with a slash")
+	}
+
+	if cfg.Server.Templates != "" {
+		if stat, err := os.Stat(cfg.Server.Templates); err != nil {
+			check("server.templates", err)
+		} else if !stat.IsDir() {
+			invalid("server.templates", "%s is not a directory", cfg.Server.Templates)
+		}
+	}
+
+	check("server.errors", errorsList.Validate(cfg))
+
+	if !core.ValidStatus(cfg.Server.FallbackCode) {
+		invalid("server.fallback_code", "must be between 200 and %d", errorsList.MaxCode)
+	}
+
+	if cfg.Server.FallbackStatus != 0 && !core.ValidStatus(cfg.Server.FallbackStatus) {
+		invalid("server.fallback_status", "must be between 200 and %d", errorsList.MaxCode)
+	}
+
+	serverTLS := validatePair(check, invalid, "server", cfg.Server.Cert, cfg.Server.Key)
+	metricsTLS := validatePair(check, invalid, "metrics", cfg.Metrics.Cert, cfg.Metrics.Key)
+
+	if policy, err := certs.NewClientPolicy(cfg.Server.ClientAuth); err != nil {
+		check("server.client_auth", err)
+	} else if policy.Enabled() && !serverTLS {
+		invalid("server.client_auth", "requires a server certificate")
+	}
+
+	if policy, err := certs.NewClientPolicy(cfg.Metrics.ClientAuth); err != nil {
+		check("metrics.client_auth", err)
+	} else if policy.Enabled() && !metricsTLS {
+		invalid("metrics.client_auth", "requires a metrics certificate")
+	}
+
+	switch cfg.Metrics.Auth {
+	case "", metricsHandler.AuthBearer, metricsHandler.AuthBasic, metricsHandler.AuthAny:
+	default:
+		invalid("metrics.auth", "unknown mode %s", cfg.Metrics.Auth)
+	}
+
+	for _, file := range cfg.Metrics.TokenFiles {
+		if _, err := os.Stat(file); err != nil {
+			check("metrics.token_files", err)
+		}
+	}
+
+	tlsConfig, err := router.TLSConfig(cfg)
+	check("server.tls", err)
+
+	if cfg.Server.HTTP3 {
+		if !serverTLS {
+			invalid("server.http3", "requires a server certificate and key")
+		}
+
+		// h2c only applies to cleartext listeners, HTTP/3 only to TLS listeners
+		if cfg.Server.H2C {
+			invalid("server.http3", "can't be combined with server.h2c")
+		}
+
+		if tlsConfig != nil && tlsConfig.MaxVersion != 0 && tlsConfig.MaxVersion < tls.VersionTLS13 {
+			invalid("server.http3", "requires TLS 1.3 to be allowed")
+		}
+
+		if !listener.IsTCP(cfg.Server.Addr) {
+			invalid("server.http3", "requires a TCP server address")
+		}
+	}
+
+	for _, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{"server.read_timeout", int64(cfg.Server.ReadTimeout)},
+		{"server.read_header_timeout", int64(cfg.Server.ReadHeaderTimeout)},
+		{"server.write_timeout", int64(cfg.Server.WriteTimeout)},
+		{"server.idle_timeout", int64(cfg.Server.IdleTimeout)},
+		{"server.max_header_bytes", int64(cfg.Server.MaxHeaderBytes)},
+		{"metrics.read_timeout", int64(cfg.Metrics.ReadTimeout)},
+		{"metrics.read_header_timeout", int64(cfg.Metrics.ReadHeaderTimeout)},
+		{"metrics.write_timeout", int64(cfg.Metrics.WriteTimeout)},
+		{"metrics.idle_timeout", int64(cfg.Metrics.IdleTimeout)},
+		{"metrics.max_header_bytes", int64(cfg.Metrics.MaxHeaderBytes)},
+		{"metrics.handler_timeout", int64(cfg.Metrics.HandlerTimeout)},
+		{"metrics.shutdown_timeout", int64(cfg.Metrics.ShutdownTimeout)},
+		{"server.handler_timeout", int64(cfg.Server.HandlerTimeout)},
+		{"server.shutdown_timeout", int64(cfg.Server.ShutdownTimeout)},
+		{"server.drain_timeout", int64(cfg.Server.DrainTimeout)},
+		{"upstream.ttl", int64(cfg.Upstream.TTL)},
+		{"upstream.failure_ttl", int64(cfg.Upstream.FailureTTL)},
+		{"upstream.max_size", cfg.Upstream.MaxSize},
+		{"snapshot.max_age", int64(cfg.Snapshot.MaxAge)},
+	} {
+		if limit.value < 0 {
+			invalid(limit.key, "must not be negative")
+		}
+	}
+
+	// a zero timeout of the clients would wait for hanging servers forever
+	if cfg.Upstream.Timeout <= 0 {
+		invalid("upstream.timeout", "must be positive")
+	}
+
+	if cfg.Snapshot.Timeout <= 0 {
+		invalid("snapshot.timeout", "must be positive")
+	}
+
+	check("rate_limit", ratelimit.Validate(cfg))
+	check("cors", cors.Validate(cfg))
+	check("details.fields", core.ValidateDetails(cfg))
+	check("themes", core.ValidateThemes(cfg))
+	check("rules", core.ValidateRules(cfg))
+	check("redirects", core.ValidateRedirects(cfg))
+	check("upstream.targets", core.ValidateUpstream(cfg))
+
+	if cfg.Snapshot.Enabled {
+		check("snapshot", core.ValidateSnapshot(cfg))
+	}
+	check("access_log", accesslog.Validate(cfg))
+
+	for _, sampling := range []struct {
+		key   string
+		ratio float64
+	}{
+		{"access_log.sampling.1xx", cfg.AccessLog.Sampling.Informational},
+		{"access_log.sampling.2xx", cfg.AccessLog.Sampling.Success},
+		{"access_log.sampling.3xx", cfg.AccessLog.Sampling.Redirection},
+		{"access_log.sampling.4xx", cfg.AccessLog.Sampling.ClientError},
+		{"access_log.sampling.5xx", cfg.AccessLog.Sampling.ServerError},
+	} {
+		if sampling.ratio < 0 || sampling.ratio > 1 {
+			invalid(sampling.key, "must be between 0 and 1")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePair checks that cert and key are configured together and match,
+// it returns if TLS is enabled by the pair.
+func validatePair(
+	check func(string, error),
+	invalid func(string, string, ...interface{}),
+	prefix, cert, key string,
+) bool {
+	switch {
+	case cert == "" && key == "":
+		return false
+	case cert == "":
+		invalid(prefix+".cert", "required if a key is configured")
+
+		return false
+	case key == "":
+		invalid(prefix+".key", "required if a cert is configured")
+
+		return false
+	}
+
+	if _, err := tls.LoadX509KeyPair(cert, key); err != nil {
+		check(prefix+".cert", err)
+	}
+
+	return true
+}
@@ -1,48 +1,266 @@
 package config
 
 import (
+	"io"
+	"sync/atomic"
+	"time"
+
 	"github.com/owncloud-ops/errors/pkg/metrics"
+	"github.com/owncloud-ops/errors/pkg/snapshot"
+	"github.com/owncloud-ops/errors/pkg/tokens"
+	"github.com/owncloud-ops/errors/pkg/upstream"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// ClientAuth defines the client certificate verification.
+type ClientAuth struct {
+	Mode     string   `mapstructure:"mode"`
+	CA       string   `mapstructure:"ca"`
+	Subjects []string `mapstructure:"subjects"`
+	SANs     []string `mapstructure:"sans"`
+}
+
 // Server defines the server configuration.
 type Server struct {
-	Addr          string `mapstructure:"addr"`
-	Host          string `mapstructure:"host"`
-	Pprof         bool   `mapstructure:"pprof"`
-	Root          string `mapstructure:"root"`
-	Cert          string `mapstructure:"cert"`
-	Key           string `mapstructure:"key"`
-	StrictCurves  bool   `mapstructure:"strict_curves"`
-	StrictCiphers bool   `mapstructure:"strict_ciphers"`
-	Templates     string `mapstructure:"templates"`
-	Errors        string `mapstructure:"errors"`
-	ErrorsTitle   string `mapstructure:"errors_title"`
+	Addr          string     `mapstructure:"addr"`
+	SocketMode    string     `mapstructure:"socket_mode"`
+	Host          string     `mapstructure:"host"`
+	Pprof         bool       `mapstructure:"pprof"`
+	H2C           bool       `mapstructure:"h2c"`
+	HTTP3         bool       `mapstructure:"http3"`
+	Root          string     `mapstructure:"root"`
+	Cert          string     `mapstructure:"cert"`
+	Key           string     `mapstructure:"key"`
+	StrictCurves  bool       `mapstructure:"strict_curves"`
+	StrictCiphers bool       `mapstructure:"strict_ciphers"`
+	TLSProfile    string     `mapstructure:"tls_profile"`
+	TLSMinVersion string     `mapstructure:"tls_min_version"`
+	TLSMaxVersion string     `mapstructure:"tls_max_version"`
+	TLSCurves     []string   `mapstructure:"tls_curves"`
+	TLSCiphers    []string   `mapstructure:"tls_ciphers"`
+	Templates     string     `mapstructure:"templates"`
+	Theme         string     `mapstructure:"theme"`
+	Errors        []string   `mapstructure:"errors"`
+	ErrorsTitle   string     `mapstructure:"errors_title"`
+	ClientAuth    ClientAuth `mapstructure:"client_auth"`
+
+	FallbackCode   int `mapstructure:"fallback_code"`
+	FallbackStatus int `mapstructure:"fallback_status"`
+
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+	HandlerTimeout    time.Duration `mapstructure:"handler_timeout"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+	DrainTimeout      time.Duration `mapstructure:"drain_timeout"`
+	Draining          *atomic.Bool  `mapstructure:"-"`
 }
 
 // Metrics defines the metrics server configuration.
 type Metrics struct {
-	Addr    string `mapstructure:"addr"`
-	Token   string `mapstructure:"token"`
-	Reg     *prometheus.Registry
-	Metrics metrics.Metrics
+	Addr       string            `mapstructure:"addr"`
+	SocketMode string            `mapstructure:"socket_mode"`
+	Token      string            `mapstructure:"token" secret:"true"`
+	Tokens     map[string]string `mapstructure:"tokens" secret:"true"`
+	TokenFiles []string          `mapstructure:"token_files"`
+	Auth       string            `mapstructure:"auth"`
+	Store      *tokens.Store     `mapstructure:"-"`
+	Cert       string            `mapstructure:"cert"`
+	Key        string            `mapstructure:"key"`
+	ClientAuth ClientAuth        `mapstructure:"client_auth"`
+	Reg        *prometheus.Registry
+	Metrics    metrics.Metrics
+
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+	HandlerTimeout    time.Duration `mapstructure:"handler_timeout"`
+	ShutdownTimeout   time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// RateLimit defines the rate limiting configuration.
+type RateLimit struct {
+	Key   string        `mapstructure:"key"`
+	Rate  float64       `mapstructure:"rate"`
+	Burst int           `mapstructure:"burst"`
+	TTL   time.Duration `mapstructure:"ttl"`
+}
+
+// CORS defines the cross-origin resource sharing policy.
+type CORS struct {
+	Origins     []string      `mapstructure:"origins"`
+	Methods     []string      `mapstructure:"methods"`
+	Headers     []string      `mapstructure:"headers"`
+	Exposed     []string      `mapstructure:"exposed"`
+	Credentials bool          `mapstructure:"credentials"`
+	MaxAge      time.Duration `mapstructure:"max_age"`
+	Paths       []string      `mapstructure:"paths"`
+}
+
+// Security defines the security headers.
+type Security struct {
+	CSP                       string `mapstructure:"csp"`
+	ReferrerPolicy            string `mapstructure:"referrer_policy"`
+	PermissionsPolicy         string `mapstructure:"permissions_policy"`
+	CrossOriginOpenerPolicy   string `mapstructure:"cross_origin_opener_policy"`
+	CrossOriginEmbedderPolicy string `mapstructure:"cross_origin_embedder_policy"`
+	CrossOriginResourcePolicy string `mapstructure:"cross_origin_resource_policy"`
+	FrameOptions              string `mapstructure:"frame_options"`
+	XSSProtection             string `mapstructure:"xss_protection"`
+	HSTS                      string `mapstructure:"hsts"`
+}
+
+// Details defines the request details shown on error pages.
+type Details struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Fields        []string `mapstructure:"fields"`
+	MaxLength     int      `mapstructure:"max_length"`
+	InternalHosts []string `mapstructure:"internal_hosts"`
+}
+
+// AccessLog defines the access log configuration.
+type AccessLog struct {
+	Enabled    bool              `mapstructure:"enabled"`
+	Output     string            `mapstructure:"output"`
+	File       string            `mapstructure:"file"`
+	MaxSize    int               `mapstructure:"max_size"`
+	MaxBackups int               `mapstructure:"max_backups"`
+	MaxAge     int               `mapstructure:"max_age"`
+	Compress   bool              `mapstructure:"compress"`
+	Syslog     string            `mapstructure:"syslog"`
+	Fields     []string          `mapstructure:"fields"`
+	Sampling   AccessLogSampling `mapstructure:"sampling"`
+	Writer     io.WriteCloser    `mapstructure:"-"`
+}
+
+// AccessLogSampling defines the ratio of logged requests per status class.
+type AccessLogSampling struct {
+	Informational float64 `mapstructure:"1xx"`
+	Success       float64 `mapstructure:"2xx"`
+	Redirection   float64 `mapstructure:"3xx"`
+	ClientError   float64 `mapstructure:"4xx"`
+	ServerError   float64 `mapstructure:"5xx"`
+}
+
+// Rule defines a rewrite of the status code or the message of error pages,
+// empty match lists match all requests.
+type Rule struct {
+	Name       string   `mapstructure:"name"`
+	Codes      []string `mapstructure:"codes"`
+	Hosts      []string `mapstructure:"hosts"`
+	Namespaces []string `mapstructure:"namespaces"`
+	Paths      []string `mapstructure:"paths"`
+	Code       int      `mapstructure:"code"`
+	Message    string   `mapstructure:"message"`
+}
+
+// Redirect defines a redirect answering matching errors instead of a page,
+// empty match lists match all requests.
+type Redirect struct {
+	Name   string   `mapstructure:"name"`
+	Codes  []string `mapstructure:"codes"`
+	Hosts  []string `mapstructure:"hosts"`
+	Paths  []string `mapstructure:"paths"`
+	Regex  string   `mapstructure:"regex"`
+	Target string   `mapstructure:"target"`
+	Status int      `mapstructure:"status"`
+}
+
+// Upstream defines custom error pages fetched from upstreams.
+type Upstream struct {
+	Timeout    time.Duration    `mapstructure:"timeout"`
+	TTL        time.Duration    `mapstructure:"ttl"`
+	FailureTTL time.Duration    `mapstructure:"failure_ttl"`
+	MaxSize    int64            `mapstructure:"max_size"`
+	CSP        string           `mapstructure:"csp"`
+	Targets    []UpstreamTarget `mapstructure:"targets"`
+	Cache      *upstream.Cache  `mapstructure:"-"`
+}
+
+// UpstreamTarget defines the upstream of custom error pages for matching
+// hosts and codes, empty match lists match all requests. An empty CSP
+// falls back to the policy of all upstreams.
+type UpstreamTarget struct {
+	Name  string   `mapstructure:"name"`
+	Hosts []string `mapstructure:"hosts"`
+	Codes []string `mapstructure:"codes"`
+	URL   string   `mapstructure:"url"`
+	CSP   string   `mapstructure:"csp"`
+}
+
+// Theme defines the theme of the custom templates used for matching hosts.
+type Theme struct {
+	Name  string   `mapstructure:"name"`
+	Hosts []string `mapstructure:"hosts"`
+}
+
+// Snapshot defines the snapshots served for failing upstreams.
+type Snapshot struct {
+	Enabled     bool            `mapstructure:"enabled"`
+	Dir         string          `mapstructure:"dir"`
+	Codes       []string        `mapstructure:"codes"`
+	Hosts       []string        `mapstructure:"hosts"`
+	URLs        []string        `mapstructure:"urls"`
+	MaxSize     int64           `mapstructure:"max_size"`
+	MaxPageSize int64           `mapstructure:"max_page_size"`
+	MaxAge      time.Duration   `mapstructure:"max_age"`
+	Timeout     time.Duration   `mapstructure:"timeout"`
+	CSP         string          `mapstructure:"csp"`
+	Store       *snapshot.Store `mapstructure:"-"`
+}
+
+// Health defines the health check configuration.
+type Health struct {
+	Target     string `mapstructure:"target"`
+	Addr       string `mapstructure:"addr"`
+	TLS        bool   `mapstructure:"tls"`
+	CA         string `mapstructure:"ca"`
+	Cert       string `mapstructure:"cert"`
+	Key        string `mapstructure:"key"`
+	ServerName string `mapstructure:"server_name"`
+	Insecure   bool   `mapstructure:"insecure"`
+	Page       int    `mapstructure:"page"`
+	Format     string `mapstructure:"format"`
+	Output     string `mapstructure:"output"`
 }
 
-// Logs defines the level and color for log configuration.
+// Logs defines the level, format and color for log configuration.
 type Logs struct {
-	Level  string `mapstructure:"level"`
-	Pretty bool   `mapstructure:"pretty"`
-	Color  bool   `mapstructure:"color"`
+	Level     string `mapstructure:"level"`
+	Pretty    bool   `mapstructure:"pretty"`
+	Color     bool   `mapstructure:"color"`
+	Format    string `mapstructure:"format"`
+	Caller    bool   `mapstructure:"caller"`
+	Timestamp string `mapstructure:"timestamp"`
 }
 
 // Config defines the general configuration.
 type Config struct {
-	Server  Server  `mapstructure:"server"`
-	Metrics Metrics `mapstructure:"metrics"`
-	Logs    Logs    `mapstructure:"log"`
+	Server    Server     `mapstructure:"server"`
+	Metrics   Metrics    `mapstructure:"metrics"`
+	RateLimit RateLimit  `mapstructure:"rate_limit"`
+	CORS      CORS       `mapstructure:"cors"`
+	Security  Security   `mapstructure:"security"`
+	Details   Details    `mapstructure:"details"`
+	AccessLog AccessLog  `mapstructure:"access_log"`
+	Themes    []Theme    `mapstructure:"themes"`
+	Rules     []Rule     `mapstructure:"rules"`
+	Redirects []Redirect `mapstructure:"redirects"`
+	Upstream  Upstream   `mapstructure:"upstream"`
+	Snapshot  Snapshot   `mapstructure:"snapshot"`
+	Health    Health     `mapstructure:"health"`
+	Logs      Logs       `mapstructure:"log"`
 }
 
 // Load initializes a default configuration struct.
 func Load() *Config {
-	return &Config{}
+	return &Config{
+		Server: Server{
+			Draining: &atomic.Bool{},
+		},
+	}
 }
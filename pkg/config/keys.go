@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the value of secrets when the configuration gets printed.
+const Redacted = "<redacted>"
+
+// ErrUnknownKey defines the error if the config file contains an unknown key.
+var ErrUnknownKey = errors.New("unknown config key")
+
+//nolint:gochecknoglobals
+var listIndex = regexp.MustCompile(`\[\d+\]`)
+
+// Keys returns all keys of the configuration, keys of maps end with a wildcard
+// and the fields of list items are prefixed by the list key with brackets.
+func Keys() []string {
+	result := []string{}
+
+	walk(reflect.TypeOf(Config{}), "", func(key string, _ reflect.StructField) {
+		result = append(result, key)
+	})
+
+	sort.Strings(result)
+
+	return result
+}
+
+// UnknownKeys returns the keys of the nested settings which are not part of
+// the configuration, keys within lists are reported with their index like
+// redirects[0].status.
+func UnknownKeys(settings map[string]interface{}) []string {
+	known := Keys()
+	result := []string{}
+
+	for _, key := range flatten(settings, "") {
+		if !knownKey(known, listIndex.ReplaceAllString(strings.ToLower(key), "[]")) {
+			result = append(result, key)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// flatten returns the leaf keys of the settings, lists of maps are flattened
+// per item while other lists are leafs.
+func flatten(settings map[string]interface{}, prefix string) []string {
+	result := []string{}
+
+	for name, value := range settings {
+		key := prefix + name
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if len(value) == 0 {
+				result = append(result, key)
+
+				continue
+			}
+
+			result = append(result, flatten(value, key+".")...)
+		case []interface{}:
+			result = append(result, key)
+
+			for i, item := range value {
+				if nested, ok := item.(map[string]interface{}); ok {
+					result = append(result, flatten(nested, fmt.Sprintf("%s[%d].", key, i))...)
+				}
+			}
+		default:
+			result = append(result, key)
+		}
+	}
+
+	return result
+}
+
+// Redact converts the configuration to a map keyed like the config file, the
+// values of fields tagged as secret get replaced.
+func Redact(cfg *Config) map[string]interface{} {
+	return redactStruct(reflect.ValueOf(*cfg))
+}
+
+// walk calls the callback for every leaf key of the type.
+func walk(t reflect.Type, prefix string, fn func(string, reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := prefix + name
+
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)):
+			walk(field.Type, key+".", fn)
+		case field.Type.Kind() == reflect.Map:
+			fn(key+".*", field)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			fn(key, field)
+			walk(field.Type.Elem(), key+"[].", fn)
+		default:
+			fn(key, field)
+		}
+	}
+}
+
+func knownKey(known []string, key string) bool {
+	for _, candidate := range known {
+		if candidate == key {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(candidate, "*"); ok && (strings.HasPrefix(key, prefix) || key+"." == prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactStruct(v reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{}, v.NumField())
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("mapstructure")
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+
+		switch {
+		case field.Tag.Get("secret") == "true":
+			result[name] = redactValue(value)
+		case value.Kind() == reflect.Struct:
+			result[name] = redactStruct(value)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			items := make([]map[string]interface{}, 0, value.Len())
+
+			for j := 0; j < value.Len(); j++ {
+				items = append(items, redactStruct(value.Index(j)))
+			}
+
+			result[name] = items
+		case value.Type() == reflect.TypeOf(time.Duration(0)):
+			result[name] = time.Duration(value.Int()).String()
+		default:
+			result[name] = value.Interface()
+		}
+	}
+
+	return result
+}
+
+func redactValue(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Map:
+		result := make(map[string]string, value.Len())
+
+		for _, key := range value.MapKeys() {
+			result[fmt.Sprint(key.Interface())] = Redacted
+		}
+
+		return result
+	case reflect.String:
+		if value.Len() == 0 {
+			return ""
+		}
+	}
+
+	return Redacted
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     []string
+	}{
+		{
+			name: "known",
+			settings: map[string]interface{}{
+				"server":  map[string]interface{}{"addr": ":8080", "client_auth": map[string]interface{}{"mode": "none"}},
+				"metrics": map[string]interface{}{"tokens": map[string]interface{}{"prometheus": "secret"}},
+				"redirects": []interface{}{
+					map[string]interface{}{"name": "docs", "Status": 301},
+				},
+			},
+			want: []string{},
+		},
+		{
+			name: "empty",
+			settings: map[string]interface{}{
+				"metrics": map[string]interface{}{"tokens": map[string]interface{}{}},
+				"rules":   []interface{}{},
+			},
+			want: []string{},
+		},
+		{
+			name: "typos",
+			settings: map[string]interface{}{
+				"server": map[string]interface{}{"errrors": []interface{}{"/errors"}},
+				"redirects": []interface{}{
+					map[string]interface{}{"name": "docs", "status": 301},
+					map[string]interface{}{"name": "login", "stauts": 302},
+				},
+				"upstream": map[string]interface{}{
+					"targets": []interface{}{
+						map[string]interface{}{"urll": "http://localhost"},
+					},
+				},
+			},
+			want: []string{"redirects[1].stauts", "server.errrors", "upstream.targets[0].urll"},
+		},
+		{
+			name: "list of values",
+			settings: map[string]interface{}{
+				"cors": map[string]interface{}{"origins": []interface{}{"*"}},
+				"rules": []interface{}{
+					map[string]interface{}{"codes": []interface{}{"5xx"}, "hosts": map[string]interface{}{"a": "b"}},
+				},
+			},
+			want: []string{"rules[0].hosts.a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnknownKeys(tt.settings); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// SchemaDraft defines the JSON Schema dialect of the generated schemas.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches durations accepted by time.ParseDuration.
+const durationPattern = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// Schema generates a JSON Schema for the config file from the configuration structs.
+func Schema() map[string]interface{} {
+	result := schemaOf(reflect.TypeOf(Config{}))
+
+	result["$schema"] = SchemaDraft
+	result["title"] = "errors configuration"
+
+	return result
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]interface{}{
+			"type":    nullable("string"),
+			"pattern": durationPattern,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{}, t.NumField())
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get("mapstructure")
+
+			if name == "" || name == "-" {
+				continue
+			}
+
+			properties[name] = schemaOf(field.Type)
+		}
+
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaOf(t.Elem()),
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  nullable("array"),
+			"items": schemaOf(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{
+			"type": nullable("boolean"),
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{
+			"type": nullable("integer"),
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{
+			"type": nullable("number"),
+		}
+	}
+
+	return map[string]interface{}{
+		"type": nullable("string"),
+	}
+}
+
+// nullable allows empty values, which are used within YAML for unset keys.
+func nullable(name string) []string {
+	return []string{name, "null"}
+}
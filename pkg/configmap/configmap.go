@@ -0,0 +1,99 @@
+// Package configmap reads directories which may be mounted from Kubernetes config maps.
+package configmap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DataDir defines the symlink pointing to the current revision of a mounted config map.
+const DataDir = "..data"
+
+// File defines a file found by Walk.
+type File struct {
+	// Name is the slash separated path relative to the walked directory.
+	Name string
+
+	// Path is the location the content should be read from.
+	Path string
+}
+
+// Resolve returns the directory holding the current revision of a mounted
+// config map, other directories are returned unchanged.
+//
+// Kubernetes updates config maps by swapping the DataDir symlink, reading all
+// files through the resolved revision avoids mixing files of two revisions.
+func Resolve(dir string) string {
+	target, err := filepath.EvalSymlinks(filepath.Join(dir, DataDir))
+	if err != nil {
+		return dir
+	}
+
+	return target
+}
+
+// Walk returns all files below the directory in lexical order. Symlinks are
+// followed, hidden entries like the revisions of config maps are skipped.
+func Walk(dir string) ([]File, error) {
+	result := []File{}
+
+	if err := walk(Resolve(dir), "", map[string]bool{}, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func walk(dir, prefix string, seen map[string]bool, result *[]File) error {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	// symlinks may point to a parent directory
+	if seen[real] {
+		return nil
+	}
+
+	seen[real] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		stat, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			// dangling symlinks are left behind by removed keys
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if stat.IsDir() {
+			if err := walk(path, prefix+entry.Name()+"/", seen, result); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		*result = append(*result, File{
+			Name: prefix + entry.Name(),
+			Path: path,
+		})
+	}
+
+	return nil
+}
@@ -1,90 +1,334 @@
 package errors
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 
 	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/configmap"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// SeverityInfo marks errors which are expected and harmless.
+	SeverityInfo = "info"
+
+	// SeverityWarning marks errors which users can resolve on their own.
+	SeverityWarning = "warning"
+
+	// SeverityError marks errors which need attention.
+	SeverityError = "error"
+
+	// SeverityCritical marks errors which need immediate attention.
+	SeverityCritical = "critical"
+)
+
+// Severities defines the known severities, entries may omit the severity.
+//
+//nolint:gochecknoglobals
+var Severities = []string{
+	SeverityInfo,
+	SeverityWarning,
+	SeverityError,
+	SeverityCritical,
+}
+
+// LinkSchemes defines the schemes allowed for links of entries.
+//
+//nolint:gochecknoglobals
+var LinkSchemes = []string{
+	"http",
+	"https",
+	"mailto",
+}
+
+var (
+	// ErrSeverity defines the error if the severity of an entry is unknown.
+	ErrSeverity = errors.New("unknown severity")
+
+	// ErrType defines the error if the type of an entry is not a valid URI.
+	ErrType = errors.New("invalid type uri")
+
+	// ErrLink defines the error if the link of an entry is not a web or mail URI.
+	ErrLink = errors.New("invalid link uri")
+
+	// ErrCode defines the error if a code is not a valid status code.
+	ErrCode = errors.New("invalid status code")
+)
+
+// DefaultLayer names the layer of the embedded defaults.
+const DefaultLayer = "default"
+
 // List defines the list of available errors.
-type List map[int]string
+type List map[int]Entry
+
+// Layered defines an entry together with the layer which defined it.
+type Layered struct {
+	Entry
+	Layer string
+}
+
+// Entry defines a single error, within YAML it can be defined by the message
+// only or as mapping with all optional fields.
+type Entry struct {
+	Message     string   `yaml:"message"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description"`
+	Hints       []string `yaml:"hints"`
+	Link        string   `yaml:"link"`
+	Severity    string   `yaml:"severity"`
+	Type        string   `yaml:"type"`
+	Delete      bool     `yaml:"delete"`
+}
+
+// UnmarshalYAML accepts a plain message as well as a mapping.
+func (e *Entry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*e = Entry{}
+
+		return node.Decode(&e.Message)
+	}
 
-// Load initializes the errors list.
+	type plain Entry
+
+	return node.Decode((*plain)(e))
+}
+
+// Validate checks the severity, the link and the type URI of the entry.
+func (e Entry) Validate() error {
+	if e.Severity != "" && !slices.Contains(Severities, e.Severity) {
+		return fmt.Errorf("%w: %s", ErrSeverity, e.Severity)
+	}
+
+	if e.Link != "" {
+		if uri, err := url.Parse(e.Link); err != nil || !slices.Contains(LinkSchemes, uri.Scheme) || (uri.Opaque == "" && uri.Host == "") {
+			return fmt.Errorf("%w: %s", ErrLink, e.Link)
+		}
+	}
+
+	if e.Type != "" {
+		if uri, err := url.Parse(e.Type); err != nil || uri.Scheme == "" {
+			return fmt.Errorf("%w: %s", ErrType, e.Type)
+		}
+	}
+
+	return nil
+}
+
+// Load initializes the errors list, the custom layers get merged into the
+// defaults. Broken layers get logged and skipped.
 func Load(cfg *config.Config) List {
-	defaultErrors := List{
-		400: "The server cannot or will not process the request.",
-		401: "You are not authorized to request this resource.",
-		403: "The server is refusing to respond to your request.",
-		404: "The page you are looking for was not found.",
-		405: "The server doesn't accept your request method.",
-		406: "The headers can't be accepted by the server.",
-		407: "The client must first authenticate itself with the proxy.",
-		408: "The server timed out waiting for the request.",
-		409: "The request could not be processed because of conflict.",
-		410: "The resource is no longer available and will not be available again.",
-		411: "The request did not specify the length of its content.",
-		412: "The server does not meet one of the preconditions.",
-		413: "The request is larger than the server is willing or able to process.",
-		414: "The URI provided was too long for the server to process.",
-		415: "The request has a media type which the server does not support.",
-		416: "The requested range can't be satisfied.",
-		417: "The server cannot meet the requirements of the Expect header.",
-		422: "The request was well-formed but was unable to be followed.",
-		423: "The resource that is being accessed is locked.",
-		424: "The request failed due to failure of a previous request.",
-		426: "The client should switch to a different protocol.",
-		428: "The origin server requires the request to be conditional.",
-		429: "The user has sent too many requests in a given amount of time.",
-		431: "The server is unwilling to process the request.",
-		451: "The request have been blocked because of legal reasons.",
-		500: "I messed it up, but this is not your fault.",
-		501: "The server does not recognize the request method.",
-		502: "The server received an invalid response from upstream.",
-		503: "The server is currently unavailable, this is a temporary state.",
-		504: "The server did not receive a timely response from upstream.",
-		505: "The server does not support the HTTP protocol version.",
-		506: "Transparent content negotiation results in a circular reference.",
-		507: "The server is unable to store the result of the request.",
-		508: "The server detected an infinite loop while processing the request.",
-		510: "Extensions to the request are required for the server to fulfil it.",
-		511: "The client needs to authenticate to gain network access.",
-	}
-
-	if cfg.Server.Errors != "" {
-		result := List{}
-
-		if _, err := os.Stat(cfg.Server.Errors); os.IsNotExist(err) {
-			log.Error().
-				Err(err).
-				Str("file", cfg.Server.Errors).
-				Msg("Failed to find custom errors")
-
-			return defaultErrors
+	layered, err := Resolve(cfg)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Strs("paths", cfg.Server.Errors).
+			Msg("Failed to load custom errors")
+	}
+
+	result := make(List, len(layered))
+
+	for code, entry := range layered {
+		result[code] = entry.Entry
+	}
+
+	return result
+}
+
+// Resolve merges the embedded defaults with all configured layers, every
+// path can be a file or a directory with YAML files which are applied in
+// lexical order. Later layers win per code, a null entry or an entry with
+// delete set removes the code.
+func Resolve(cfg *config.Config) (map[int]Layered, error) {
+	result := make(map[int]Layered)
+
+	for code, entry := range defaults() {
+		result[code] = Layered{
+			Entry: entry,
+			Layer: DefaultLayer,
 		}
+	}
 
-		content, err := os.ReadFile(cfg.Server.Errors)
+	var errs []error
+
+	for _, path := range cfg.Server.Errors {
+		files, err := layerFiles(path)
 		if err != nil {
-			log.Error().
-				Err(err).
-				Str("file", cfg.Server.Errors).
-				Msg("Failed to read custom errors")
+			errs = append(errs, err)
 
-			return defaultErrors
+			continue
 		}
 
-		if err := yaml.Unmarshal(content, &result); err != nil {
-			log.Error().
-				Err(err).
-				Str("file", cfg.Server.Errors).
-				Msg("Failed to parse custom errors")
+		for _, file := range files {
+			layer, err := readLayer(file)
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			for code, entry := range layer {
+				if entry == nil || entry.Delete {
+					delete(result, code)
 
-			return defaultErrors
+					continue
+				}
+
+				result[code] = Layered{
+					Entry: *entry,
+					Layer: file.Name,
+				}
+			}
 		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func defaults() List {
+	return List{
+		400: {Message: "The server cannot or will not process the request."},
+		401: {Message: "You are not authorized to request this resource."},
+		403: {Message: "The server is refusing to respond to your request."},
+		404: {Message: "The page you are looking for was not found."},
+		405: {Message: "The server doesn't accept your request method."},
+		406: {Message: "The headers can't be accepted by the server."},
+		407: {Message: "The client must first authenticate itself with the proxy."},
+		408: {Message: "The server timed out waiting for the request."},
+		409: {Message: "The request could not be processed because of conflict."},
+		410: {Message: "The resource is no longer available and will not be available again."},
+		411: {Message: "The request did not specify the length of its content."},
+		412: {Message: "The server does not meet one of the preconditions."},
+		413: {Message: "The request is larger than the server is willing or able to process."},
+		414: {Message: "The URI provided was too long for the server to process."},
+		415: {Message: "The request has a media type which the server does not support."},
+		416: {Message: "The requested range can't be satisfied."},
+		417: {Message: "The server cannot meet the requirements of the Expect header."},
+		422: {Message: "The request was well-formed but was unable to be followed."},
+		423: {Message: "The resource that is being accessed is locked."},
+		424: {Message: "The request failed due to failure of a previous request."},
+		426: {Message: "The client should switch to a different protocol."},
+		428: {Message: "The origin server requires the request to be conditional."},
+		429: {Message: "The user has sent too many requests in a given amount of time."},
+		431: {Message: "The server is unwilling to process the request."},
+		451: {Message: "The request have been blocked because of legal reasons."},
+		500: {Message: "I messed it up, but this is not your fault."},
+		501: {Message: "The server does not recognize the request method."},
+		502: {Message: "The server received an invalid response from upstream."},
+		503: {Message: "The server is currently unavailable, this is a temporary state."},
+		504: {Message: "The server did not receive a timely response from upstream."},
+		505: {Message: "The server does not support the HTTP protocol version."},
+		506: {Message: "Transparent content negotiation results in a circular reference."},
+		507: {Message: "The server is unable to store the result of the request."},
+		508: {Message: "The server detected an infinite loop while processing the request."},
+		510: {Message: "Extensions to the request are required for the server to fulfil it."},
+		511: {Message: "The client needs to authenticate to gain network access."},
+
+		// non-standard codes of nginx and Cloudflare
+		444: {Message: "The server closed the connection without sending a response."},
+		494: {Message: "The request headers are too large for the server."},
+		495: {Message: "The client certificate could not be verified."},
+		496: {Message: "The server requires a client certificate which was not provided."},
+		497: {Message: "A plain HTTP request was sent to the HTTPS port."},
+		499: {Message: "The client closed the connection before the server answered."},
+		520: {Message: "The origin server returned an empty, unknown or unexpected response."},
+		521: {Message: "The origin server refused the connection."},
+		522: {Message: "The connection to the origin server timed out."},
+		523: {Message: "The origin server could not be reached."},
+		524: {Message: "The origin server did not send a timely response."},
+		525: {Message: "The SSL handshake with the origin server failed."},
+		526: {Message: "The certificate of the origin server could not be validated."},
+		527: {Message: "The connection to the origin server was interrupted."},
+	}
+}
 
-		return result
+// layerFiles returns the file or the YAML files below the directory in lexical
+// order, directories mounted from config maps are read from a single revision.
+func layerFiles(path string) ([]configmap.File, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find custom errors: %w", err)
 	}
 
-	return defaultErrors
+	if !stat.IsDir() {
+		return []configmap.File{{Name: path, Path: path}}, nil
+	}
+
+	files, err := configmap.Walk(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom errors dir: %w", err)
+	}
+
+	result := []configmap.File{}
+
+	for _, file := range files {
+		switch filepath.Ext(file.Name) {
+		case ".yaml", ".yml":
+			result = append(result, configmap.File{
+				Name: filepath.Join(path, file.Name),
+				Path: file.Path,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// readLayer parses a single file, deleted codes are represented by nil.
+func readLayer(file configmap.File) (map[int]*Entry, error) {
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom errors: %w", err)
+	}
+
+	nodes := map[int]yaml.Node{}
+
+	if err := yaml.Unmarshal(content, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse custom errors %s: %w", file.Name, err)
+	}
+
+	result := make(map[int]*Entry, len(nodes))
+
+	for code, node := range nodes {
+		if !ValidCode(code) {
+			return nil, fmt.Errorf("%w: %d in %s", ErrCode, code, file.Name)
+		}
+
+		if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+			result[code] = nil
+
+			continue
+		}
+
+		entry := &Entry{}
+
+		if err := node.Decode(entry); err != nil {
+			return nil, fmt.Errorf("failed to parse custom error %d in %s: %w", code, file.Name, err)
+		}
+
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid custom error %d in %s: %w", code, file.Name, err)
+		}
+
+		result[code] = entry
+	}
+
+	return result, nil
+}
+
+// Validate checks if all custom errors layers can be read and parsed.
+func Validate(cfg *config.Config) error {
+	_, err := Resolve(cfg)
+
+	return err
 }
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEntryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		err   error
+	}{
+		{name: "empty", entry: Entry{}},
+		{name: "complete", entry: Entry{
+			Severity: SeverityWarning,
+			Link:     "https://status.example.com/",
+			Type:     "urn:problem:not-found",
+		}},
+		{name: "mail link", entry: Entry{Link: "mailto:support@example.com"}},
+		{name: "unknown severity", entry: Entry{Severity: "fatal"}, err: ErrSeverity},
+		{name: "severity case", entry: Entry{Severity: "Warning"}, err: ErrSeverity},
+		{name: "relative link", entry: Entry{Link: "/status"}, err: ErrLink},
+		{name: "script link", entry: Entry{Link: "javascript:alert(1)"}, err: ErrLink},
+		{name: "hostless link", entry: Entry{Link: "https:///status"}, err: ErrLink},
+		{name: "malformed link", entry: Entry{Link: "https://%zz"}, err: ErrLink},
+		{name: "relative type", entry: Entry{Type: "not-found"}, err: ErrType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.entry.Validate(); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSchemaSeverities(t *testing.T) {
+	entry := Schema()["additionalProperties"].(map[string]interface{})["oneOf"].([]interface{})[1].(map[string]interface{})
+	severity := entry["properties"].(map[string]interface{})["severity"].(map[string]interface{})
+
+	enum, ok := severity["enum"].([]string)
+	if !ok || !reflect.DeepEqual(enum, Severities) {
+		t.Fatalf("expected schema enum %v, got %v", Severities, severity["enum"])
+	}
+
+	for _, value := range enum {
+		if err := (Entry{Severity: value}).Validate(); err != nil {
+			t.Errorf("expected %s of the schema to be valid: %v", value, err)
+		}
+	}
+}
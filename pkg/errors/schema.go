@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+// Schema generates a JSON Schema for custom errors files.
+func Schema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema": config.SchemaDraft,
+		"title":   "errors catalog",
+		"type":    "object",
+		"propertyNames": map[string]interface{}{
+			"pattern": "^[1-5][0-9]{2}$",
+		},
+		"additionalProperties": map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{
+					"type": []string{"string", "null"},
+				},
+				map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"message":     map[string]interface{}{"type": "string"},
+						"title":       map[string]interface{}{"type": "string"},
+						"description": map[string]interface{}{"type": "string"},
+						"hints":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						"link":        map[string]interface{}{"type": "string", "format": "uri", "pattern": "^(" + strings.Join(LinkSchemes, "|") + "):"},
+						"severity":    map[string]interface{}{"type": "string", "enum": Severities},
+						"type":        map[string]interface{}{"type": "string", "format": "uri"},
+						"delete":      map[string]interface{}{"type": "boolean"},
+					},
+					"additionalProperties": false,
+				},
+			},
+		},
+	}
+}
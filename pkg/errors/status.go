@@ -0,0 +1,58 @@
+package errors
+
+import (
+	"net/http"
+)
+
+const (
+	// MinCode defines the lowest status code accepted within the catalog.
+	MinCode = 100
+
+	// MaxCode defines the highest status code accepted within the catalog.
+	MaxCode = 599
+)
+
+// vendorStatus defines the names of non-standard codes used by proxies and CDNs
+// like nginx and Cloudflare, http.StatusText doesn't know them.
+//
+//nolint:gochecknoglobals
+var vendorStatus = map[int]string{
+	444: "No Response",
+	494: "Request Header Too Large",
+	495: "SSL Certificate Error",
+	496: "SSL Certificate Required",
+	497: "HTTP Request Sent to HTTPS Port",
+	499: "Client Closed Request",
+	520: "Web Server Returned an Unknown Error",
+	521: "Web Server Is Down",
+	522: "Connection Timed Out",
+	523: "Origin Is Unreachable",
+	524: "A Timeout Occurred",
+	525: "SSL Handshake Failed",
+	526: "Invalid SSL Certificate",
+	527: "Railgun Error",
+}
+
+// ValidCode checks if the code is within the range of HTTP status codes.
+func ValidCode(code int) bool {
+	return code >= MinCode && code <= MaxCode
+}
+
+// StatusText returns the name of standard and common vendor codes, it is
+// empty for unknown codes.
+func StatusText(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return text
+	}
+
+	return vendorStatus[code]
+}
+
+// Known checks if the list or the status texts define a message for the code.
+func (l List) Known(code int) bool {
+	if entry, ok := l[code]; ok && entry.Message != "" {
+		return true
+	}
+
+	return StatusText(code) != ""
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+const (
+	// OriginalURIHeader defines the header used by ingress for the original URI.
+	OriginalURIHeader = "X-Original-URI"
+
+	// ServiceNameHeader defines the header used by ingress for the backend service.
+	ServiceNameHeader = "X-Service-Name"
+
+	// RequestIDHeader defines the header used by ingress for the request ID.
+	RequestIDHeader = "X-Request-ID"
+
+	// NamespaceHeader defines the header used by ingress for the namespace.
+	NamespaceHeader = "X-Namespace"
+)
+
+const (
+	// DetailRequestID shows the request ID provided by the ingress.
+	DetailRequestID = "request_id"
+
+	// DetailTime shows the time the error page has been rendered.
+	DetailTime = "time"
+
+	// DetailURI shows the path of the original URI without query.
+	DetailURI = "uri"
+
+	// DetailHost shows the host of the original request.
+	DetailHost = "host"
+
+	// DetailService shows the backend service, only for internal hosts.
+	DetailService = "service"
+)
+
+// ErrDetailField defines the error if a details field is unknown.
+var ErrDetailField = errors.New("unknown details field")
+
+// Detail represents a single sanitized request detail.
+type Detail struct {
+	Name  string
+	Label string
+	Value string
+}
+
+// ValidateDetails checks the details configuration.
+func ValidateDetails(cfg *config.Config) error {
+	for _, field := range cfg.Details.Fields {
+		switch field {
+		case DetailRequestID, DetailTime, DetailURI, DetailHost, DetailService:
+			continue
+		}
+
+		return fmt.Errorf("%w: %s", ErrDetailField, field)
+	}
+
+	return nil
+}
+
+// RequestDetails collects the allowlisted details of the request in the
+// configured order, every value is reduced to printable ASCII and truncated.
+func RequestDetails(cfg *config.Config, req *http.Request) []Detail {
+	if !cfg.Details.Enabled || req == nil {
+		return nil
+	}
+
+	host := OriginalHost(req)
+	result := make([]Detail, 0, len(cfg.Details.Fields))
+
+	for _, field := range cfg.Details.Fields {
+		var label, value string
+
+		switch field {
+		case DetailRequestID:
+			label, value = "Request ID", req.Header.Get(RequestIDHeader)
+		case DetailTime:
+			label, value = "Time", time.Now().UTC().Format(time.RFC3339)
+		case DetailURI:
+			label, value = "URI", originalPath(req)
+		case DetailHost:
+			label, value = "Host", host
+		case DetailService:
+			if !internalHost(cfg.Details.InternalHosts, host) {
+				continue
+			}
+
+			label, value = "Service", req.Header.Get(ServiceNameHeader)
+		}
+
+		if value = sanitize(value, cfg.Details.MaxLength); value == "" {
+			continue
+		}
+
+		result = append(result, Detail{
+			Name:  field,
+			Label: label,
+			Value: value,
+		})
+	}
+
+	return result
+}
+
+// OriginalHost returns the host of the original request, it prefers the
+// original URI of the ingress over forwarded headers and the request host.
+func OriginalHost(req *http.Request) string {
+	if uri, err := url.Parse(req.Header.Get(OriginalURIHeader)); err == nil && uri.Host != "" {
+		return uri.Hostname()
+	}
+
+	if host := req.Header.Get("X-Forwarded-Host"); host != "" {
+		return host
+	}
+
+	return req.Host
+}
+
+func originalPath(req *http.Request) string {
+	raw := req.Header.Get(OriginalURIHeader)
+	if raw == "" {
+		return ""
+	}
+
+	if uri, err := url.Parse(raw); err == nil {
+		return uri.EscapedPath()
+	}
+
+	raw, _, _ = strings.Cut(raw, "?")
+
+	return raw
+}
+
+// internalHost treats loopback and private addresses as well as hosts
+// matching one of the configured patterns as internal.
+func internalHost(patterns []string, host string) bool {
+	if host == "" {
+		return false
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate()
+	}
+
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(host)); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sanitize(value string, limit int) string {
+	result := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' || strings.ContainsRune("\"\\<>`", r) {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	result = strings.TrimSpace(result)
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit] + "..."
+	}
+
+	return result
+}
@@ -1,72 +1,277 @@
 package core
 
 import (
+	"bytes"
+	"fmt"
+	"html/template"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/owncloud-ops/errors/pkg/config"
 	"github.com/owncloud-ops/errors/pkg/errors"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/accesslog"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
 	"github.com/owncloud-ops/errors/pkg/templates"
 	"github.com/rs/zerolog/log"
 )
 
 // Payload represents the payload for template rendering.
 type Payload struct {
-	Status int
-	Error  string
-	Title  string
+	Status      int
+	Error       string
+	Title       string
+	Description string
+	Hints       []string
+	Link        string
+	Severity    string
+	Type        string
+	Details     []Detail
 }
 
+// RespondWithErrorPage writes the error page for the code in the format
+// preferred by the client.
 func RespondWithErrorPage(
 	req *http.Request,
 	writer http.ResponseWriter,
 	cfg *config.Config,
-	pageCode int,
+	code int,
 ) {
-	errorTemplate := "html.tmpl"
+	format := HTMLContentType
+
+	if ClientWantFormat(req) == JSONContentType {
+		format = JSONContentType
+	}
+
+	writer.Header().Set("X-Robots-Tag", "noindex") // block Search indexing
+
+	if name, location, status, ok := matchRedirect(cfg, req, code); ok {
+		log.Debug().
+			Str("redirect", name).
+			Int("code", code).
+			Str("location", location).
+			Msg("Redirected error by rule")
+
+		cfg.Metrics.Metrics.IncrementRedirected(name, codeLabel(code))
+
+		if record := accesslog.FromContext(req.Context()); record != nil {
+			record.OriginalCode = code
+		}
+
+		writer.Header().Set("Location", location)
+		writer.WriteHeader(status)
+
+		return
+	}
+
+	startedAt := time.Now()
+	originalCode, message := code, ""
+
+	if name, rule, ok := matchRule(cfg, req, code); ok {
+		if rule.Code != 0 {
+			code = rule.Code
+		}
+
+		message = rule.Message
+
+		log.Debug().
+			Str("rule", name).
+			Int("code", code).
+			Int("original_code", originalCode).
+			Msg("Rewrote error page by rule")
+
+		cfg.Metrics.Metrics.IncrementRewritten(name, codeLabel(originalCode), codeLabel(code))
+	}
+
 	availableErrors := errors.Load(cfg)
-	msg, ok := availableErrors[pageCode]
+	pageCode, status := resolveCode(cfg, availableErrors, code)
 
-	if !ok {
-		msg = http.StatusText(pageCode)
+	// snapshots of the original page are preferred over any error page
+	if page, ok := loadSnapshot(cfg, req, originalCode, format); ok {
+		if record := accesslog.FromContext(req.Context()); record != nil {
+			record.OriginalCode = originalCode
+			record.Format = FormatName(format)
+			record.Template = SnapshotTemplate
+			record.Render = time.Since(startedAt)
+		}
+
+		if message == "" {
+			message = availableErrors[pageCode].Message
+		}
+
+		if message == "" {
+			message = errors.StatusText(pageCode)
+		}
+
+		writeSnapshot(writer, cfg, page, status, message, header.Nonce(req.Context()))
+
+		return
 	}
 
-	clientWant := ClientWantFormat(req)
+	// pages of upstreams are preferred, the templates are the fallback
+	if name, csp, page, ok := fetchUpstream(cfg, req, status, format); ok {
+		if record := accesslog.FromContext(req.Context()); record != nil {
+			record.OriginalCode = originalCode
+			record.Format = FormatName(format)
+			record.Template = "upstream:" + name
+			record.Render = time.Since(startedAt)
+		}
 
-	writer.Header().Set("X-Robots-Tag", "noindex") // block Search indexing
-	SetClientFormat(writer, PlainTextContentType)  // set default content type
+		writeUpstream(writer, page, status, csp, header.Nonce(req.Context()))
 
-	switch {
-	case clientWant == JSONContentType: // JSON
-		{
-			errorTemplate = "json.tmpl"
+		return
+	}
+
+	content, name, err := renderPage(
+		cfg,
+		availableErrors,
+		pageCode,
+		message,
+		format,
+		templateDirs(cfg, req),
+		header.Nonce(req.Context()),
+		RequestDetails(cfg, req),
+	)
 
-			SetClientFormat(writer, JSONContentType)
+	if record := accesslog.FromContext(req.Context()); record != nil {
+		record.OriginalCode = originalCode
+		record.Format = FormatName(format)
+		record.Template = name
+		record.Locale = templateLocale(name)
+		record.Render = time.Since(startedAt)
+	}
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int("code", pageCode).
+			Msg("Failed to execute template")
+
+		SetClientFormat(writer, PlainTextContentType)
+		writer.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(writer, "template for error page not exists")
+
+		return
+	}
+
+	SetClientFormat(writer, format)
+	writer.WriteHeader(status)
+	_, _ = writer.Write(content)
+}
+
+// RenderErrorPage renders the error page for a code in the requested format
+// with the templates of the root level, the nonce is exposed to the templates
+// via the cspNonce function.
+func RenderErrorPage(
+	cfg *config.Config,
+	pageCode int,
+	format ContentType,
+	nonce string,
+	details []Detail,
+) ([]byte, error) {
+	content, _, err := renderPage(cfg, errors.Load(cfg), pageCode, "", format, nil, nonce, details)
+
+	return content, err
+}
+
+// ValidStatus checks if the code can be sent as final response status,
+// informational codes would only be sent as interim response.
+func ValidStatus(code int) bool {
+	return code >= http.StatusOK && code <= errors.MaxCode
+}
+
+// resolveCode returns the page and the status for a requested code. Unknown
+// and invalid codes get the fallback page, unknown codes keep their status
+// unless a fallback status is configured.
+func resolveCode(cfg *config.Config, availableErrors errors.List, code int) (int, int) {
+	status := cfg.Server.FallbackStatus
+
+	switch {
+	case !ValidStatus(code):
+		if status == 0 {
+			status = cfg.Server.FallbackCode
 		}
 
-	default: // HTML
-		{
-			SetClientFormat(writer, HTMLContentType)
+		return cfg.Server.FallbackCode, status
+	case !availableErrors.Known(code):
+		if status == 0 {
+			status = code
 		}
+
+		return cfg.Server.FallbackCode, status
+	}
+
+	return code, code
+}
+
+// renderPage renders the page of the catalog with the first template found
+// within the directories and returns the name of the template, a non-empty
+// message replaces the message of the entry.
+func renderPage(
+	cfg *config.Config,
+	availableErrors errors.List,
+	pageCode int,
+	message string,
+	format ContentType,
+	dirs []string,
+	nonce string,
+	details []Detail,
+) ([]byte, string, error) {
+	entry, ok := availableErrors[pageCode]
+
+	if !ok || entry.Message == "" {
+		entry.Message = errors.StatusText(pageCode)
+	}
+
+	if message != "" {
+		entry.Message = message
 	}
 
-	writer.WriteHeader(pageCode)
+	// the title of the entry is more specific than the global title
+	if entry.Title == "" {
+		entry.Title = cfg.Server.ErrorsTitle
+	}
+
+	tpls, err := templates.Load(cfg).Clone()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to clone templates: %w", err)
+	}
+
+	tpls.Funcs(template.FuncMap{
+		"cspNonce": func() string {
+			return nonce
+		},
+	})
 
-	if err := templates.Load(cfg).ExecuteTemplate(
-		writer,
+	errorTemplate := lookupTemplate(tpls, dirs, TemplateName(format))
+	buf := &bytes.Buffer{}
+
+	if err := tpls.ExecuteTemplate(
+		buf,
 		errorTemplate,
 		Payload{
-			Status: pageCode,
-			Error:  msg,
-			Title:  cfg.Server.ErrorsTitle,
+			Status:      pageCode,
+			Error:       entry.Message,
+			Title:       entry.Title,
+			Description: entry.Description,
+			Hints:       entry.Hints,
+			Link:        entry.Link,
+			Severity:    entry.Severity,
+			Type:        entry.Type,
+			Details:     details,
 		},
 	); err != nil {
-		log.Error().
-			Err(err).
-			Str("template", errorTemplate).
-			Msg("Failed to execute template")
+		return nil, errorTemplate, fmt.Errorf("failed to execute %s: %w", errorTemplate, err)
+	}
 
-		writer.WriteHeader(http.StatusInternalServerError)
-		_, _ = io.WriteString(writer, "template "+errorTemplate+" not exists")
+	return buf.Bytes(), errorTemplate, nil
+}
+
+// TemplateName returns the name of the template used for the format.
+func TemplateName(format ContentType) string {
+	if format == JSONContentType {
+		return "json.tmpl"
 	}
+
+	return "html.tmpl"
 }
@@ -83,6 +83,20 @@ func SetClientFormat(writer http.ResponseWriter, t ContentType) {
 	}
 }
 
+// FormatName returns a short name of the content type.
+func FormatName(t ContentType) string {
+	switch t {
+	case JSONContentType:
+		return "json"
+	case HTMLContentType:
+		return "html"
+	case PlainTextContentType:
+		return "text"
+	}
+
+	return "unknown"
+}
+
 func mimeTypeToContentType(mimeType string) ContentType {
 	switch {
 	case strings.Contains(mimeType, "application/json"), strings.Contains(mimeType, "text/json"):
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	errorsList "github.com/owncloud-ops/errors/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrRedirect defines the error if a redirect is invalid.
+var ErrRedirect = errors.New("invalid redirect")
+
+// redirectPatterns caches the compiled regular expressions of the redirects.
+//
+//nolint:gochecknoglobals
+var redirectPatterns sync.Map
+
+// ValidateRedirects checks the codes, patterns, targets and status of the redirects.
+func ValidateRedirects(cfg *config.Config) error {
+	for i, redirect := range cfg.Redirects {
+		name := redirectName(redirect, i)
+
+		if redirect.Target == "" {
+			return fmt.Errorf("%w: %s: requires a target", ErrRedirect, name)
+		}
+
+		switch redirect.Status {
+		case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return fmt.Errorf("%w: %s: status must be 301, 302, 307 or 308", ErrRedirect, name)
+		}
+
+		for _, pattern := range redirect.Codes {
+			if _, _, ok := codeRange(pattern); !ok {
+				return fmt.Errorf("%w: %s: invalid code pattern %s", ErrRedirect, name, pattern)
+			}
+		}
+
+		for _, patterns := range [][]string{redirect.Hosts, redirect.Paths} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+					return fmt.Errorf("%w: %s: invalid pattern %s", ErrRedirect, name, pattern)
+				}
+			}
+		}
+
+		// the host is taken from client headers, unrestricted it would redirect anywhere
+		if strings.Contains(redirect.Target, "{host}") && !restrictsHosts(redirect.Hosts) {
+			return fmt.Errorf("%w: %s: {host} requires including hosts", ErrRedirect, name)
+		}
+
+		if redirect.Regex != "" {
+			if _, err := redirectPattern(redirect.Regex); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrRedirect, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchRedirect returns the name, the location and the status of the first
+// redirect matching the code and the original request, invalid codes taken
+// from the path never match.
+func matchRedirect(cfg *config.Config, req *http.Request, code int) (string, string, int, bool) {
+	if !errorsList.ValidCode(code) {
+		return "", "", 0, false
+	}
+
+	host := originalHostname(req)
+	uriPath := originalPath(req)
+
+	for i, redirect := range cfg.Redirects {
+		if !matchCode(redirect.Codes, code) {
+			continue
+		}
+
+		if !matchPatterns(redirect.Hosts, host, matchGlob) {
+			continue
+		}
+
+		if !matchPatterns(redirect.Paths, uriPath, matchPath) {
+			continue
+		}
+
+		target := redirect.Target
+
+		if redirect.Regex != "" {
+			pattern, err := redirectPattern(redirect.Regex)
+			if err != nil {
+				continue
+			}
+
+			match := pattern.FindStringSubmatchIndex(uriPath)
+			if match == nil {
+				continue
+			}
+
+			target = string(pattern.ExpandString(nil, target, uriPath, match))
+		}
+
+		name := redirectName(redirect, i)
+		location := expandTarget(target, req, host)
+
+		// captured paths must not turn a local target into another host
+		if strings.HasPrefix(redirect.Target, "/") && !strings.HasPrefix(redirect.Target, "//") &&
+			(strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\")) {
+			log.Warn().
+				Str("redirect", name).
+				Str("location", location).
+				Msg("Skipped redirect to another host")
+
+			continue
+		}
+
+		status := redirect.Status
+		if status == 0 {
+			status = http.StatusFound
+		}
+
+		return name, location, status, true
+	}
+
+	return "", "", 0, false
+}
+
+func redirectName(redirect config.Redirect, index int) string {
+	if redirect.Name != "" {
+		return redirect.Name
+	}
+
+	return fmt.Sprintf("redirects[%d]", index)
+}
+
+// restrictsHosts checks if the patterns include hosts, exclusions alone still
+// match any host.
+func restrictsHosts(patterns []string) bool {
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "!") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redirectPattern(expr string) (*regexp.Regexp, error) {
+	if cached, ok := redirectPatterns.Load(expr); ok {
+		return cached.(*regexp.Regexp), nil //nolint:forcetypeassert
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+
+	redirectPatterns.Store(expr, pattern)
+
+	return pattern, nil
+}
+
+// expandTarget replaces the placeholders of the target, the original URI and
+// URL get query escaped to be used as return parameters.
+func expandTarget(target string, req *http.Request, host string) string {
+	query := ""
+
+	if uri, err := url.Parse(req.Header.Get(OriginalURIHeader)); err == nil && uri.RawQuery != "" {
+		query = "?" + uri.RawQuery
+	}
+
+	return strings.NewReplacer(
+		"{host}", host,
+		"{path}", originalPath(req),
+		"{query}", query,
+		"{uri}", url.QueryEscape(req.Header.Get(OriginalURIHeader)),
+		"{url}", url.QueryEscape(originalURL(req)),
+	).Replace(target)
+}
+
+// originalURL returns the absolute URL of the original request, the scheme
+// is taken from the forwarded headers and defaults to https.
+func originalURL(req *http.Request) string {
+	raw := req.Header.Get(OriginalURIHeader)
+
+	if uri, err := url.Parse(raw); err == nil && uri.IsAbs() {
+		return raw
+	}
+
+	scheme := req.Header.Get("X-Forwarded-Proto")
+
+	if scheme == "" {
+		scheme = req.Header.Get("X-Scheme")
+	}
+
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + OriginalHost(req) + raw
+}
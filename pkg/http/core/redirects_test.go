@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/metrics"
+)
+
+func TestValidateRedirects(t *testing.T) {
+	tests := []struct {
+		name     string
+		redirect config.Redirect
+		err      error
+	}{
+		{name: "target", redirect: config.Redirect{Target: "https://login.example.com/?return={url}"}},
+		{name: "missing target", redirect: config.Redirect{}, err: ErrRedirect},
+		{name: "status", redirect: config.Redirect{Target: "/", Status: http.StatusOK}, err: ErrRedirect},
+		{name: "host with hosts", redirect: config.Redirect{Target: "https://{host}/login", Hosts: []string{"*.example.com"}}},
+		{name: "host without hosts", redirect: config.Redirect{Target: "https://{host}/login?return={url}"}, err: ErrRedirect},
+		{name: "host with exclusions", redirect: config.Redirect{Target: "https://{host}/login", Hosts: []string{"!evil.com"}}, err: ErrRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.Redirects = []config.Redirect{tt.redirect}
+
+			if err := ValidateRedirects(cfg); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestMatchRedirect(t *testing.T) {
+	cfg := config.Load()
+	cfg.Metrics.Metrics = metrics.NewMetrics()
+	cfg.Redirects = []config.Redirect{
+		{Name: "login", Target: "https://{host}/login", Hosts: []string{"*.example.com"}},
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		code     int
+		location string
+	}{
+		{name: "allowed host", host: "app.example.com", code: http.StatusUnauthorized, location: "https://app.example.com/login"},
+		{name: "foreign host", host: "evil.com", code: http.StatusUnauthorized},
+		{name: "invalid code", host: "app.example.com", code: 99999999},
+		{name: "zero code", host: "app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Forwarded-Host", tt.host)
+
+			_, location, _, ok := matchRedirect(cfg, req, tt.code)
+			if ok != (tt.location != "") || location != tt.location {
+				t.Fatalf("expected %q, got %q", tt.location, location)
+			}
+		})
+	}
+}
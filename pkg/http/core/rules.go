@@ -0,0 +1,208 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	errorsList "github.com/owncloud-ops/errors/pkg/errors"
+)
+
+// ErrRule defines the error if a rewrite rule is invalid.
+var ErrRule = errors.New("invalid rule")
+
+// ValidateRules checks the codes, patterns and targets of the rewrite rules.
+func ValidateRules(cfg *config.Config) error {
+	for i, rule := range cfg.Rules {
+		name := ruleName(rule, i)
+
+		if rule.Code == 0 && rule.Message == "" {
+			return fmt.Errorf("%w: %s: requires a code or a message", ErrRule, name)
+		}
+
+		if rule.Code != 0 && !ValidStatus(rule.Code) {
+			return fmt.Errorf("%w: %s: code %d is not between 200 and %d", ErrRule, name, rule.Code, errorsList.MaxCode)
+		}
+
+		for _, pattern := range rule.Codes {
+			if _, _, ok := codeRange(pattern); !ok {
+				return fmt.Errorf("%w: %s: invalid code pattern %s", ErrRule, name, pattern)
+			}
+		}
+
+		for _, patterns := range [][]string{rule.Hosts, rule.Namespaces, rule.Paths} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+					return fmt.Errorf("%w: %s: invalid pattern %s", ErrRule, name, pattern)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchRule returns the name and the first rule matching the code and the
+// original request, invalid codes taken from the path never match.
+func matchRule(cfg *config.Config, req *http.Request, code int) (string, config.Rule, bool) {
+	if !errorsList.ValidCode(code) {
+		return "", config.Rule{}, false
+	}
+
+	host := originalHostname(req)
+
+	for i, rule := range cfg.Rules {
+		if !matchCode(rule.Codes, code) {
+			continue
+		}
+
+		if !matchPatterns(rule.Hosts, host, matchGlob) {
+			continue
+		}
+
+		if !matchPatterns(rule.Namespaces, req.Header.Get(NamespaceHeader), matchGlob) {
+			continue
+		}
+
+		if !matchPatterns(rule.Paths, originalPath(req), matchPath) {
+			continue
+		}
+
+		return ruleName(rule, i), rule, true
+	}
+
+	return "", config.Rule{}, false
+}
+
+// originalHostname returns the original host without port.
+func originalHostname(req *http.Request) string {
+	host := OriginalHost(req)
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return host
+}
+
+func ruleName(rule config.Rule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+
+	return fmt.Sprintf("rules[%d]", index)
+}
+
+// codeLabel returns the code as metric label, invalid codes share a single
+// label to keep the cardinality bounded.
+func codeLabel(code int) string {
+	if !errorsList.ValidCode(code) {
+		return "invalid"
+	}
+
+	return strconv.Itoa(code)
+}
+
+func matchCode(patterns []string, code int) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if low, high, ok := codeRange(pattern); ok && code >= low && code <= high {
+			return true
+		}
+	}
+
+	return false
+}
+
+// codeRange parses code patterns like 404, 5xx or 500-503.
+func codeRange(pattern string) (int, int, bool) {
+	low, high := 0, 0
+
+	if first, last, ok := strings.Cut(pattern, "-"); ok {
+		var err1, err2 error
+
+		low, err1 = strconv.Atoi(strings.TrimSpace(first))
+		high, err2 = strconv.Atoi(strings.TrimSpace(last))
+
+		if err1 != nil || err2 != nil {
+			return 0, 0, false
+		}
+	} else if class, ok := strings.CutSuffix(strings.ToLower(pattern), "xx"); ok && len(class) == 1 {
+		digit, err := strconv.Atoi(class)
+		if err != nil {
+			return 0, 0, false
+		}
+
+		low, high = digit*100, digit*100+99 //nolint:gomnd
+	} else {
+		code, err := strconv.Atoi(pattern)
+		if err != nil {
+			return 0, 0, false
+		}
+
+		low, high = code, code
+	}
+
+	if low > high || !errorsList.ValidCode(low) || !errorsList.ValidCode(high) {
+		return 0, 0, false
+	}
+
+	return low, high, true
+}
+
+// matchPatterns checks the value against the patterns, patterns prefixed by
+// an exclamation mark exclude values. Without including patterns all values
+// match which are not excluded.
+func matchPatterns(patterns []string, value string, match func(string, string) bool) bool {
+	included, including := false, false
+
+	for _, pattern := range patterns {
+		if excluded, ok := strings.CutPrefix(pattern, "!"); ok {
+			if match(excluded, value) {
+				return false
+			}
+
+			continue
+		}
+
+		including = true
+
+		if match(pattern, value) {
+			included = true
+		}
+	}
+
+	return included || !including
+}
+
+func matchGlob(pattern, value string) bool {
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+
+	return err == nil && ok
+}
+
+// matchPath matches the path or one of its parents, that way /admin covers
+// all paths below.
+func matchPath(pattern, value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for current := value; ; current = path.Dir(current) {
+		if ok, err := path.Match(pattern, current); err == nil && ok {
+			return true
+		}
+
+		if current == "/" || current == "." {
+			return false
+		}
+	}
+}
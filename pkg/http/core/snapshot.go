@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
+	"github.com/owncloud-ops/errors/pkg/snapshot"
+	"github.com/owncloud-ops/errors/pkg/templates"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SnapshotTemplate defines the template of the banner injected into snapshots.
+	SnapshotTemplate = "banner.tmpl"
+
+	// SnapshotServed counts snapshots served for failing upstreams.
+	SnapshotServed = "served"
+
+	// SnapshotMissing counts requests without a stored snapshot.
+	SnapshotMissing = "missing"
+
+	// SnapshotExpired counts snapshots older than the maximum age.
+	SnapshotExpired = "expired"
+)
+
+// ErrSnapshot defines the error if the snapshot configuration is invalid.
+var ErrSnapshot = errors.New("invalid snapshot")
+
+// SnapshotPayload represents the payload for the snapshot banner.
+type SnapshotPayload struct {
+	Status int
+	Error  string
+	URL    string
+	Time   time.Time
+}
+
+// ValidateSnapshot checks the directory, sizes, patterns and URLs of the snapshots.
+func ValidateSnapshot(cfg *config.Config) error {
+	if cfg.Snapshot.Dir == "" {
+		return fmt.Errorf("%w: requires a directory", ErrSnapshot)
+	}
+
+	if cfg.Snapshot.MaxSize <= 0 || cfg.Snapshot.MaxPageSize <= 0 {
+		return fmt.Errorf("%w: sizes must be positive", ErrSnapshot)
+	}
+
+	for _, pattern := range cfg.Snapshot.Codes {
+		if _, _, ok := codeRange(pattern); !ok {
+			return fmt.Errorf("%w: invalid code pattern %s", ErrSnapshot, pattern)
+		}
+	}
+
+	for _, pattern := range cfg.Snapshot.Hosts {
+		if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+			return fmt.Errorf("%w: invalid pattern %s", ErrSnapshot, pattern)
+		}
+	}
+
+	for _, url := range cfg.Snapshot.URLs {
+		if _, err := snapshot.ParseURL(url); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadSnapshot returns the snapshot of the original request if the code and
+// the host match, snapshots are only served as HTML.
+func loadSnapshot(cfg *config.Config, req *http.Request, code int, format ContentType) (*snapshot.Page, bool) {
+	if cfg.Snapshot.Store == nil || format != HTMLContentType {
+		return nil, false
+	}
+
+	host, uriPath := originalHostname(req), originalPath(req)
+
+	if uriPath == "" || !matchCode(cfg.Snapshot.Codes, code) || !matchPatterns(cfg.Snapshot.Hosts, host, matchGlob) {
+		return nil, false
+	}
+
+	page, err := cfg.Snapshot.Store.Load(host, uriPath)
+	if err != nil {
+		cfg.Metrics.Metrics.IncrementSnapshot(SnapshotMissing)
+
+		return nil, false
+	}
+
+	if cfg.Snapshot.MaxAge > 0 && time.Since(page.Time) > cfg.Snapshot.MaxAge {
+		cfg.Metrics.Metrics.IncrementSnapshot(SnapshotExpired)
+
+		return nil, false
+	}
+
+	cfg.Metrics.Metrics.IncrementSnapshot(SnapshotServed)
+
+	return page, true
+}
+
+// writeSnapshot writes the snapshot with the banner injected after the
+// opening body tag and replaces the content security policy.
+func writeSnapshot(
+	writer http.ResponseWriter,
+	cfg *config.Config,
+	page *snapshot.Page,
+	status int,
+	message string,
+	nonce string,
+) {
+	banner, err := renderBanner(cfg, SnapshotPayload{
+		Status: status,
+		Error:  message,
+		URL:    page.URL,
+		Time:   page.Time,
+	}, nonce)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("Failed to render snapshot banner")
+	}
+
+	body := page.Body
+	offset := 0
+
+	if start := bytes.Index(bytes.ToLower(body), []byte("<body")); start >= 0 {
+		if end := bytes.IndexByte(body[start:], '>'); end >= 0 {
+			offset = start + end + 1
+		}
+	}
+
+	if cfg.Snapshot.CSP != "" {
+		writer.Header().Set("Content-Security-Policy", strings.ReplaceAll(cfg.Snapshot.CSP, header.NoncePlaceholder, nonce))
+	} else {
+		writer.Header().Del("Content-Security-Policy")
+	}
+
+	writer.Header().Set("Content-Type", page.ContentType)
+	writer.Header().Set("Last-Modified", page.Time.UTC().Format(http.TimeFormat))
+	writer.WriteHeader(status)
+
+	_, _ = writer.Write(body[:offset])
+	_, _ = writer.Write(banner)
+	_, _ = writer.Write(body[offset:])
+}
+
+func renderBanner(cfg *config.Config, payload SnapshotPayload, nonce string) ([]byte, error) {
+	tpls, err := templates.Load(cfg).Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone templates: %w", err)
+	}
+
+	tpls.Funcs(template.FuncMap{
+		"cspNonce": func() string {
+			return nonce
+		},
+	})
+
+	buf := &bytes.Buffer{}
+
+	if err := tpls.ExecuteTemplate(buf, SnapshotTemplate, payload); err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", SnapshotTemplate, err)
+	}
+
+	return buf.Bytes(), nil
+}
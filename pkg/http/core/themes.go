@@ -0,0 +1,177 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+const (
+	// ThemesDir defines the directory of the themes within the custom templates.
+	ThemesDir = "themes"
+
+	// LocalesDir defines the directory of the locales within the custom templates or a theme.
+	LocalesDir = "locales"
+
+	// maxLocales limits the languages of the Accept-Language header which are looked up.
+	maxLocales = 5
+)
+
+// ErrTheme defines the error if a theme is invalid.
+var ErrTheme = errors.New("invalid theme")
+
+//nolint:gochecknoglobals
+var themeName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateThemes checks the names and patterns of the themes.
+func ValidateThemes(cfg *config.Config) error {
+	if cfg.Server.Theme != "" && !themeName.MatchString(cfg.Server.Theme) {
+		return fmt.Errorf("%w: server.theme: %s is not a plain directory name", ErrTheme, cfg.Server.Theme)
+	}
+
+	for i, theme := range cfg.Themes {
+		name := fmt.Sprintf("themes[%d]", i)
+
+		if !themeName.MatchString(theme.Name) {
+			return fmt.Errorf("%w: %s: %q is not a plain directory name", ErrTheme, name, theme.Name)
+		}
+
+		for _, pattern := range theme.Hosts {
+			if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+				return fmt.Errorf("%w: %s: invalid pattern %s", ErrTheme, name, pattern)
+			}
+		}
+	}
+
+	if (cfg.Server.Theme != "" || len(cfg.Themes) > 0) && cfg.Server.Templates == "" {
+		return fmt.Errorf("%w: requires custom templates", ErrTheme)
+	}
+
+	return nil
+}
+
+// templateDirs returns the directories of the custom templates to look up for
+// the request, ordered from the most specific one to the root.
+//
+// The theme is selected by the host, the locales by the Accept-Language header.
+// For a theme dark and the language de-CH the order is themes/dark/locales/de-ch/,
+// themes/dark/locales/de/, themes/dark/, locales/de-ch/, locales/de/ and the root.
+func templateDirs(cfg *config.Config, req *http.Request) []string {
+	theme := cfg.Server.Theme
+	host := originalHostname(req)
+
+	for _, candidate := range cfg.Themes {
+		if matchPatterns(candidate.Hosts, host, matchGlob) {
+			theme = candidate.Name
+
+			break
+		}
+	}
+
+	locales := acceptedLocales(req.Header.Get("Accept-Language"))
+	result := make([]string, 0, 2*len(locales)+2) //nolint:gomnd
+
+	if theme != "" {
+		prefix := ThemesDir + "/" + theme + "/"
+
+		for _, locale := range locales {
+			result = append(result, prefix+LocalesDir+"/"+locale+"/")
+		}
+
+		result = append(result, prefix)
+	}
+
+	for _, locale := range locales {
+		result = append(result, LocalesDir+"/"+locale+"/")
+	}
+
+	return append(result, "")
+}
+
+// lookupTemplate returns the name of the first defined template within the
+// directories, falling back to the template on the root level.
+func lookupTemplate(tpls *template.Template, dirs []string, name string) string {
+	for _, dir := range dirs {
+		if tpls.Lookup(dir+name) != nil {
+			return dir + name
+		}
+	}
+
+	return name
+}
+
+// templateLocale returns the locale of a template selected by lookupTemplate,
+// it is empty for templates outside of a locale directory.
+func templateLocale(name string) string {
+	if _, rest, ok := strings.Cut("/"+name, "/"+LocalesDir+"/"); ok {
+		locale, _, _ := strings.Cut(rest, "/")
+
+		return locale
+	}
+
+	return ""
+}
+
+// acceptedLocales parses the Accept-Language header into lower case language
+// tags ordered by weight, every tag is followed by its primary language.
+func acceptedLocales(header string) []string {
+	type locale struct {
+		tag    string
+		weight float64
+	}
+
+	locales := make([]locale, 0, maxLocales)
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		weight := 1.0
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+
+			weight = parsed
+		}
+
+		if tag == "" || tag == "*" || weight <= 0 || !themeName.MatchString(tag) {
+			continue
+		}
+
+		locales = append(locales, locale{tag, weight})
+	}
+
+	sort.SliceStable(locales, func(i, j int) bool {
+		return locales[i].weight > locales[j].weight
+	})
+
+	result := make([]string, 0, 2*maxLocales) //nolint:gomnd
+	seen := make(map[string]bool)
+
+	for i, locale := range locales {
+		if i == maxLocales {
+			break
+		}
+
+		primary, _, _ := strings.Cut(locale.tag, "-")
+
+		for _, tag := range []string{locale.tag, primary} {
+			if !seen[tag] {
+				seen[tag] = true
+				result = append(result, tag)
+			}
+		}
+	}
+
+	return result
+}
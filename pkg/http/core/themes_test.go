@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/metrics"
+)
+
+func TestAcceptedLocales(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"de-CH, fr;q=0.5", []string{"de-ch", "de", "fr"}},
+		{"fr;q=0.2, de;q=0.9, en", []string{"en", "de", "fr"}},
+		{"de-DE, de-AT;q=0.8, *;q=0.1", []string{"de-de", "de", "de-at"}},
+		{"en;q=0, ../x, es;q=invalid", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := acceptedLocales(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestValidateThemes(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates string
+		theme     string
+		themes    []config.Theme
+		err       error
+	}{
+		{name: "none"},
+		{name: "default", templates: "/templates", theme: "dark"},
+		{name: "hosts", templates: "/templates", themes: []config.Theme{{Name: "dark", Hosts: []string{"*.example.com"}}}},
+		{name: "path", templates: "/templates", theme: "../dark", err: ErrTheme},
+		{name: "empty name", templates: "/templates", themes: []config.Theme{{Hosts: []string{"*"}}}, err: ErrTheme},
+		{name: "pattern", templates: "/templates", themes: []config.Theme{{Name: "dark", Hosts: []string{"[a"}}}, err: ErrTheme},
+		{name: "templates", theme: "dark", err: ErrTheme},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.Server.Templates = tt.templates
+			cfg.Server.Theme = tt.theme
+			cfg.Themes = tt.themes
+
+			if err := ValidateThemes(cfg); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestThemeSelection(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"themes/dark/locales/de/html.tmpl": "dark de",
+		"themes/dark/html.tmpl":            "dark",
+		"themes/light/json.tmpl":           "light json",
+		"locales/fr/html.tmpl":             "fr",
+	} {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Load()
+	cfg.Metrics.Metrics = metrics.NewMetrics()
+	cfg.Server.Templates = dir
+	cfg.Themes = []config.Theme{
+		{Name: "dark", Hosts: []string{"*.dark.example.com"}},
+		{Name: "light", Hosts: []string{"*.light.example.com"}},
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		language string
+		want     string
+	}{
+		{name: "theme locale", host: "app.dark.example.com", language: "de-CH", want: "dark de"},
+		{name: "theme", host: "app.dark.example.com", language: "fr", want: "dark"},
+		{name: "locale", host: "www.example.com", language: "en, fr;q=0.8", want: "fr"},
+		{name: "root", host: "www.example.com", language: "de", want: "<!DOCTYPE html>"},
+		{name: "format", host: "app.light.example.com", language: "fr", want: "fr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(FormatHeader, "text/html")
+			req.Header.Set("X-Forwarded-Host", tt.host)
+			req.Header.Set("Accept-Language", tt.language)
+
+			rec := httptest.NewRecorder()
+			RespondWithErrorPage(req, rec, cfg, http.StatusNotFound)
+
+			if !strings.HasPrefix(rec.Body.String(), tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
+	"github.com/owncloud-ops/errors/pkg/upstream"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// UpstreamHit counts upstream pages served from the cache.
+	UpstreamHit = "hit"
+
+	// UpstreamFetched counts upstream pages fetched from the upstream.
+	UpstreamFetched = "fetched"
+
+	// UpstreamFailed counts failed upstreams which fall back to the templates.
+	UpstreamFailed = "failed"
+)
+
+// ErrUpstream defines the error if an upstream target is invalid.
+var ErrUpstream = errors.New("invalid upstream")
+
+// ValidateUpstream checks the URLs and patterns of the upstream targets.
+func ValidateUpstream(cfg *config.Config) error {
+	for i, target := range cfg.Upstream.Targets {
+		name := upstreamName(target, i)
+
+		uri, err := url.Parse(target.URL)
+		if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") || uri.Host == "" {
+			return fmt.Errorf("%w: %s: requires an http or https url", ErrUpstream, name)
+		}
+
+		for _, pattern := range target.Codes {
+			if _, _, ok := codeRange(pattern); !ok {
+				return fmt.Errorf("%w: %s: invalid code pattern %s", ErrUpstream, name, pattern)
+			}
+		}
+
+		for _, pattern := range target.Hosts {
+			if _, err := path.Match(strings.TrimPrefix(pattern, "!"), ""); err != nil {
+				return fmt.Errorf("%w: %s: invalid pattern %s", ErrUpstream, name, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchUpstream returns the page of the first upstream target matching the
+// host and the code, it fails if the upstream is down, slow or broken.
+func fetchUpstream(cfg *config.Config, req *http.Request, code int, format ContentType) (string, string, *upstream.Page, bool) {
+	if cfg.Upstream.Cache == nil {
+		return "", "", nil, false
+	}
+
+	host := originalHostname(req)
+
+	for i, target := range cfg.Upstream.Targets {
+		if !matchCode(target.Codes, code) || !matchPatterns(target.Hosts, host, matchGlob) {
+			continue
+		}
+
+		name := upstreamName(target, i)
+		accept := "text/html"
+
+		if format == JSONContentType {
+			accept = "application/json"
+		}
+
+		page, cached, err := cfg.Upstream.Cache.Fetch(
+			req.Context(),
+			strings.NewReplacer("{code}", strconv.Itoa(code), "{format}", FormatName(format)).Replace(target.URL),
+			accept,
+		)
+		if err != nil {
+			if !cached {
+				log.Warn().
+					Err(err).
+					Str("upstream", name).
+					Int("code", code).
+					Msg("Failed to fetch upstream error page")
+			}
+
+			cfg.Metrics.Metrics.IncrementUpstream(name, UpstreamFailed)
+
+			return "", "", nil, false
+		}
+
+		result := UpstreamFetched
+		if cached {
+			result = UpstreamHit
+		}
+
+		cfg.Metrics.Metrics.IncrementUpstream(name, result)
+
+		csp := target.CSP
+		if csp == "" {
+			csp = cfg.Upstream.CSP
+		}
+
+		return name, csp, page, true
+	}
+
+	return "", "", nil, false
+}
+
+// writeUpstream writes the upstream page with its own content security
+// policy, the policy of the templates would block the assets of most pages.
+func writeUpstream(writer http.ResponseWriter, page *upstream.Page, status int, csp, nonce string) {
+	if csp != "" {
+		writer.Header().Set("Content-Security-Policy", strings.ReplaceAll(csp, header.NoncePlaceholder, nonce))
+	} else {
+		writer.Header().Del("Content-Security-Policy")
+	}
+
+	writer.Header().Set("Content-Type", page.ContentType)
+	writer.WriteHeader(status)
+
+	_, _ = writer.Write(bytes.ReplaceAll(page.Body, []byte(header.NoncePlaceholder), []byte(nonce)))
+}
+
+func upstreamName(target config.UpstreamTarget, index int) string {
+	if target.Name != "" {
+		return target.Name
+	}
+
+	return fmt.Sprintf("upstream.targets[%d]", index)
+}
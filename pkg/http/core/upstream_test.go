@@ -0,0 +1,163 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/metrics"
+	"github.com/owncloud-ops/errors/pkg/upstream"
+)
+
+func upstreamConfig(url string, timeout time.Duration) *config.Config {
+	cfg := config.Load()
+	cfg.Metrics.Metrics = metrics.NewMetrics()
+	cfg.Upstream.CSP = "default-src 'none'; style-src 'nonce-{nonce}'"
+	cfg.Upstream.Targets = []config.UpstreamTarget{
+		{
+			Name:  "team",
+			Hosts: []string{"*.team.example.com"},
+			Codes: []string{"5xx"},
+			URL:   url + "/{format}/{code}",
+		},
+	}
+	cfg.Upstream.Cache = upstream.NewCache(timeout, time.Minute, time.Minute, 1024)
+
+	return cfg
+}
+
+func errorRequest(host string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(FormatHeader, "text/html")
+	req.Header.Set("X-Original-URI", "/")
+	req.Header.Set("X-Forwarded-Host", host)
+
+	return req
+}
+
+func TestUpstreamPage(t *testing.T) {
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+
+		if req.URL.Path != "/html/503" || req.Header.Get("Accept") != "text/html" {
+			writer.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "text/html")
+		_, _ = writer.Write([]byte(`<style nonce="{nonce}"></style>team`))
+	}))
+	defer server.Close()
+
+	cfg := upstreamConfig(server.URL, time.Second)
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Security-Policy", "default-src 'none'")
+		RespondWithErrorPage(errorRequest("app.team.example.com"), rec, cfg, http.StatusServiceUnavailable)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503, got %d", rec.Code)
+		}
+
+		if !strings.HasSuffix(rec.Body.String(), "team") || strings.Contains(rec.Body.String(), "{nonce}") {
+			t.Fatalf("expected upstream page with nonce, got %q", rec.Body.String())
+		}
+
+		if csp := rec.Header().Get("Content-Security-Policy"); !strings.HasPrefix(csp, "default-src 'none'; style-src 'nonce-") {
+			t.Fatalf("expected upstream policy, got %q", csp)
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 upstream request, got %d", calls.Load())
+	}
+}
+
+func TestUpstreamFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		code    int
+		timeout time.Duration
+		handler http.HandlerFunc
+	}{
+		{
+			name:    "status",
+			host:    "app.team.example.com",
+			code:    http.StatusBadGateway,
+			timeout: time.Second,
+			handler: func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			name:    "timeout",
+			host:    "app.team.example.com",
+			code:    http.StatusBadGateway,
+			timeout: 50 * time.Millisecond,
+			handler: func(_ http.ResponseWriter, req *http.Request) {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+		},
+		{
+			name:    "host",
+			host:    "www.example.com",
+			code:    http.StatusBadGateway,
+			timeout: time.Second,
+		},
+		{
+			name:    "code",
+			host:    "app.team.example.com",
+			code:    http.StatusNotFound,
+			timeout: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+				calls.Add(1)
+
+				if tt.handler == nil {
+					t.Error("unexpected upstream request")
+
+					return
+				}
+
+				tt.handler(writer, req)
+			}))
+			defer server.Close()
+
+			cfg := upstreamConfig(server.URL, tt.timeout)
+
+			for range 2 {
+				rec := httptest.NewRecorder()
+				RespondWithErrorPage(errorRequest(tt.host), rec, cfg, tt.code)
+
+				if rec.Code != tt.code {
+					t.Fatalf("expected status %d, got %d", tt.code, rec.Code)
+				}
+
+				if !strings.Contains(rec.Body.String(), "<html") {
+					t.Fatalf("expected rendered template, got %q", rec.Body.String())
+				}
+			}
+
+			// failing upstreams are skipped for the failure TTL
+			if tt.handler != nil && calls.Load() != 1 {
+				t.Fatalf("expected 1 upstream request, got %d", calls.Load())
+			}
+		})
+	}
+}
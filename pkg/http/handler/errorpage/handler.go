@@ -1,7 +1,6 @@
 package errorpages
 
 import (
-	"io"
 	"net/http"
 	"strconv"
 
@@ -15,18 +14,16 @@ import (
 func NewHandler(cfg *config.Config) http.HandlerFunc {
 	return func(writer http.ResponseWriter, req *http.Request) {
 		// defer handleMetrics(time.Now(), req.ProtoMajor, req.ProtoMinor)
-		core.SetClientFormat(writer, core.PlainTextContentType)
+		param := chi.URLParam(req, "code")
 
-		if code, err := strconv.Atoi(chi.URLParam(req, "code")); err == nil {
-			core.RespondWithErrorPage(req, writer, cfg, code)
-		} else {
-			code = http.StatusInternalServerError
-			log.Error().
-				Int("code", code).
+		// invalid codes are answered by the fallback page
+		code, err := strconv.Atoi(param)
+		if err != nil || !core.ValidStatus(code) {
+			log.Debug().
+				Str("code", param).
 				Msg("Invalid request code extracted from request")
-
-			writer.WriteHeader(code)
-			_, _ = io.WriteString(writer, "cannot extract requested code from the request")
 		}
+
+		core.RespondWithErrorPage(req, writer, cfg, code)
 	}
 }
@@ -0,0 +1,86 @@
+package errorpages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func serve(t *testing.T, cfg *config.Config, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := chi.NewRouter()
+	mux.Get("/{code}.html", NewHandler(cfg))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
+
+// series returns the label sets of all series of the metric.
+func series(t *testing.T, reg *prometheus.Registry, name string) []map[string]string {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := []map[string]string{}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			result = append(result, labels)
+		}
+	}
+
+	return result
+}
+
+func TestRulesInvalidCode(t *testing.T) {
+	cfg := config.Load()
+	cfg.Metrics.Metrics = metrics.NewMetrics()
+	cfg.Server.FallbackCode = http.StatusInternalServerError
+	cfg.Rules = []config.Rule{{Name: "all", Code: http.StatusServiceUnavailable}}
+
+	reg := prometheus.NewRegistry()
+	if err := cfg.Metrics.Metrics.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/99999999.html", status: http.StatusInternalServerError},
+		{path: "/0.html", status: http.StatusInternalServerError},
+		{path: "/404.html", status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		if rec := serve(t, cfg, tt.path); rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.status, rec.Code)
+		}
+	}
+
+	got := series(t, reg, "http_requests_rewritten_total")
+	if len(got) != 1 || got[0]["original_code"] != "404" || got[0]["code"] != "503" {
+		t.Fatalf("expected only the valid code to be rewritten, got %v", got)
+	}
+}
@@ -0,0 +1,49 @@
+package loglevel
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/logger"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const maxBodySize = 64
+
+// NewHandler creates handler to show the log level with GET and to change
+// it with PUT or POST, the level is read from the level parameter or the body.
+func NewHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+
+		if req.Method == http.MethodPut || req.Method == http.MethodPost {
+			name := req.URL.Query().Get("level")
+
+			if name == "" {
+				body, _ := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+				name = strings.TrimSpace(string(body))
+			}
+
+			level, err := logger.ParseLevel(name)
+			if err != nil {
+				writer.WriteHeader(http.StatusBadRequest)
+
+				_, _ = io.WriteString(writer, err.Error())
+
+				return
+			}
+
+			zerolog.SetGlobalLevel(level)
+
+			log.WithLevel(zerolog.NoLevel).
+				Str("log_level", level.String()).
+				Msg("Changed log level")
+		}
+
+		writer.WriteHeader(http.StatusOK)
+
+		_, _ = io.WriteString(writer, zerolog.GlobalLevel().String())
+	}
+}
@@ -2,36 +2,95 @@ package metrics
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/owncloud-ops/errors/pkg/certs"
 	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/tokens"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/hlog"
 )
 
+const (
+	// AuthBearer accepts tokens as bearer authorization.
+	AuthBearer = "bearer"
+
+	// AuthBasic accepts tokens as basic auth password with the token name as user.
+	AuthBasic = "basic"
+
+	// AuthAny accepts both bearer and basic authorization.
+	AuthAny = "any"
+)
+
+// NewHandler creates handler for the metrics, protected by the configured tokens.
 func NewHandler(cfg *config.Config) http.HandlerFunc {
 	promHandler := promhttp.HandlerFor(cfg.Metrics.Reg, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
-	token := cfg.Metrics.Token
 
-	return func(writer http.ResponseWriter, req *http.Request) {
-		if token == "" {
-			promHandler.ServeHTTP(writer, req)
+	return Protect(cfg)(promHandler).ServeHTTP
+}
 
-			return
-		}
+// Protected returns if the metrics listener requires a token or a client
+// certificate, only then endpoints changing the server get exposed.
+func Protected(cfg *config.Config) bool {
+	return tokenRequired(cfg) || cfg.Metrics.ClientAuth.Mode == certs.ClientAuthRequired
+}
 
-		header := req.Header.Get("Authorization")
+// Protect requires one of the metrics tokens as soon as any token is configured.
+func Protect(cfg *config.Config) func(next http.Handler) http.Handler {
+	protected := tokenRequired(cfg)
+	store := cfg.Metrics.Store
+	mode := cfg.Metrics.Auth
 
-		if header == "" {
-			http.Error(writer, "Invalid or missing token", http.StatusUnauthorized)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			if !protected {
+				next.ServeHTTP(writer, req)
 
-			return
-		}
+				return
+			}
+
+			name, ok := authorize(store, mode, req)
+
+			if !ok {
+				if mode == AuthBasic || mode == AuthAny {
+					writer.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+				}
+
+				http.Error(writer, "Invalid or missing token", http.StatusUnauthorized)
 
-		if header != "Bearer "+token {
-			http.Error(writer, "Invalid or missing token", http.StatusUnauthorized)
+				return
+			}
 
-			return
+			hlog.FromRequest(req).Debug().
+				Str("token", name).
+				Str("path", req.URL.Path).
+				Msg("Authorized metrics request")
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
+func tokenRequired(cfg *config.Config) bool {
+	return cfg.Metrics.Token != "" || len(cfg.Metrics.Tokens) > 0 || len(cfg.Metrics.TokenFiles) > 0
+}
+
+func authorize(store *tokens.Store, mode string, req *http.Request) (string, bool) {
+	if store == nil {
+		return "", false
+	}
+
+	if mode != AuthBasic {
+		if secret, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			return store.Match(secret)
 		}
+	}
 
-		promHandler.ServeHTTP(writer, req)
+	if mode == AuthBasic || mode == AuthAny {
+		if user, password, ok := req.BasicAuth(); ok && store.MatchNamed(user, password) {
+			return user, true
+		}
 	}
+
+	return "", false
 }
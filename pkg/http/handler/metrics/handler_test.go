@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/tokens"
+)
+
+func TestProtect(t *testing.T) {
+	basic := func(value string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		authorization string
+		status        int
+		challenge     bool
+	}{
+		{name: "bearer", mode: AuthBearer, authorization: "Bearer secret", status: http.StatusOK},
+		{name: "wrong bearer", mode: AuthBearer, authorization: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "missing", mode: AuthBearer, status: http.StatusUnauthorized},
+		{name: "lowercase scheme", mode: AuthBearer, authorization: "bearer secret", status: http.StatusUnauthorized},
+		{name: "basic for bearer", mode: AuthBearer, authorization: basic("prometheus:secret"), status: http.StatusUnauthorized},
+		{name: "basic", mode: AuthBasic, authorization: basic("prometheus:secret"), status: http.StatusOK, challenge: true},
+		{name: "basic wrong user", mode: AuthBasic, authorization: basic("grafana:secret"), status: http.StatusUnauthorized, challenge: true},
+		{name: "basic wrong password", mode: AuthBasic, authorization: basic("prometheus:wrong"), status: http.StatusUnauthorized, challenge: true},
+		{name: "basic without colon", mode: AuthBasic, authorization: basic("prometheus"), status: http.StatusUnauthorized, challenge: true},
+		{name: "basic invalid base64", mode: AuthBasic, authorization: "Basic !!!", status: http.StatusUnauthorized, challenge: true},
+		{name: "basic empty", mode: AuthBasic, authorization: "Basic ", status: http.StatusUnauthorized, challenge: true},
+		{name: "bearer for basic", mode: AuthBasic, authorization: "Bearer secret", status: http.StatusUnauthorized, challenge: true},
+		{name: "any bearer", mode: AuthAny, authorization: "Bearer secret", status: http.StatusOK, challenge: true},
+		{name: "any basic", mode: AuthAny, authorization: basic("prometheus:secret"), status: http.StatusOK, challenge: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := tokens.NewStore(map[string]string{"prometheus": "secret"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := config.Load()
+			cfg.Metrics.Tokens = map[string]string{"prometheus": "secret"}
+			cfg.Metrics.Store = store
+			cfg.Metrics.Auth = tt.mode
+
+			handler := Protect(cfg)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+
+			if challenge := rec.Header().Get("WWW-Authenticate") != ""; tt.status != http.StatusOK && challenge != tt.challenge {
+				t.Fatalf("expected challenge %v, got %v", tt.challenge, challenge)
+			}
+		})
+	}
+}
+
+func TestProtectWithoutTokens(t *testing.T) {
+	handler := Protect(config.Load())(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected unprotected access, got %d", rec.Code)
+	}
+
+	if Protected(config.Load()) {
+		t.Fatal("expected listener without tokens to be unprotected")
+	}
+}
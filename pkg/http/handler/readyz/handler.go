@@ -0,0 +1,27 @@
+package readyz
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+// NewHandler creates handler for readiness checks, it fails while the server drains.
+func NewHandler(cfg *config.Config) http.HandlerFunc {
+	return func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+
+		if cfg.Server.Draining.Load() {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			_, _ = io.WriteString(writer, http.StatusText(http.StatusServiceUnavailable))
+
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+
+		_, _ = io.WriteString(writer, http.StatusText(http.StatusOK))
+	}
+}
@@ -0,0 +1,288 @@
+// Package accesslog writes a structured access log separated from the application log.
+package accesslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// OutputStdout writes the access log to stdout.
+	OutputStdout = "stdout"
+
+	// OutputStderr writes the access log to stderr.
+	OutputStderr = "stderr"
+
+	// OutputFile writes the access log to a rotated file.
+	OutputFile = "file"
+
+	// OutputSyslog writes the access log to a local or remote syslog.
+	OutputSyslog = "syslog"
+)
+
+var (
+	// ErrOutput defines the error if the access log output is unknown or incomplete.
+	ErrOutput = errors.New("invalid access log output")
+
+	// ErrField defines the error if an access log field is unknown.
+	ErrField = errors.New("unknown access log field")
+)
+
+// Fields defines all fields supported by the access log.
+//
+//nolint:gochecknoglobals
+var Fields = []string{
+	"ip",
+	"method",
+	"path",
+	"status",
+	"size",
+	"duration",
+	"request_id",
+	"host",
+	"uri",
+	"code",
+	"original_code",
+	"namespace",
+	"ingress",
+	"service",
+	"service_port",
+	"format",
+	"locale",
+	"template",
+	"render_duration",
+	"user_agent",
+	"referer",
+}
+
+// Record collects details of the error page rendering for the access log.
+type Record struct {
+	OriginalCode int
+	Format       string
+	Template     string
+	Locale       string
+	Render       time.Duration
+}
+
+type recordKey struct{}
+
+// FromContext returns the record of the request, it is nil if the access log is disabled.
+func FromContext(ctx context.Context) *Record {
+	if record, ok := ctx.Value(recordKey{}).(*Record); ok {
+		return record
+	}
+
+	return nil
+}
+
+// Validate checks the fields and the output of the access log.
+func Validate(cfg *config.Config) error {
+	for _, field := range cfg.AccessLog.Fields {
+		if !knownField(field) {
+			return fmt.Errorf("%w: %s", ErrField, field)
+		}
+	}
+
+	switch cfg.AccessLog.Output {
+	case "", OutputStdout, OutputStderr, OutputSyslog:
+		return nil
+	case OutputFile:
+		if cfg.AccessLog.File == "" {
+			return fmt.Errorf("%w: file output requires a path", ErrOutput)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrOutput, cfg.AccessLog.Output)
+}
+
+// Open validates the access log configuration and opens the configured output.
+func Open(cfg *config.Config) (io.WriteCloser, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	switch cfg.AccessLog.Output {
+	case "", OutputStdout:
+		return nopCloser{os.Stdout}, nil
+	case OutputStderr:
+		return nopCloser{os.Stderr}, nil
+	case OutputFile:
+		return &lumberjack.Logger{
+			Filename:   cfg.AccessLog.File,
+			MaxSize:    cfg.AccessLog.MaxSize,
+			MaxBackups: cfg.AccessLog.MaxBackups,
+			MaxAge:     cfg.AccessLog.MaxAge,
+			Compress:   cfg.AccessLog.Compress,
+		}, nil
+	case OutputSyslog:
+		return openSyslog(cfg.AccessLog.Syslog)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrOutput, cfg.AccessLog.Output)
+}
+
+// Handler writes an access log entry with the configured fields for sampled requests.
+func Handler(cfg *config.Config) func(next http.Handler) http.Handler {
+	logger := zerolog.New(cfg.AccessLog.Writer).With().Timestamp().Logger()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			startedAt := time.Now()
+			record := &Record{}
+			wrapped := middleware.NewWrapResponseWriter(writer, req.ProtoMajor)
+
+			next.ServeHTTP(wrapped, req.WithContext(context.WithValue(req.Context(), recordKey{}, record)))
+
+			status := wrapped.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if !sampled(cfg.AccessLog.Sampling, status) {
+				return
+			}
+
+			event := logger.Log()
+
+			for _, field := range cfg.AccessLog.Fields {
+				switch field {
+				case "ip":
+					event.Str(field, remoteIP(req))
+				case "method":
+					event.Str(field, req.Method)
+				case "path":
+					event.Str(field, req.URL.Path)
+				case "status":
+					event.Int(field, status)
+				case "size":
+					event.Int(field, wrapped.BytesWritten())
+				case "duration":
+					event.Dur(field, time.Since(startedAt))
+				case "request_id":
+					event.Str(field, req.Header.Get("X-Request-ID"))
+				case "host":
+					event.Str(field, req.Host)
+				case "uri":
+					event.Str(field, req.Header.Get("X-Original-URI"))
+				case "code":
+					event.Str(field, req.Header.Get("X-Code"))
+				case "original_code":
+					event.Int(field, record.OriginalCode)
+				case "namespace":
+					event.Str(field, req.Header.Get("X-Namespace"))
+				case "ingress":
+					event.Str(field, req.Header.Get("X-Ingress-Name"))
+				case "service":
+					event.Str(field, req.Header.Get("X-Service-Name"))
+				case "service_port":
+					event.Str(field, req.Header.Get("X-Service-Port"))
+				case "format":
+					event.Str(field, record.Format)
+				case "locale":
+					event.Str(field, record.Locale)
+				case "template":
+					event.Str(field, record.Template)
+				case "render_duration":
+					event.Dur(field, record.Render)
+				case "user_agent":
+					event.Str(field, req.UserAgent())
+				case "referer":
+					event.Str(field, req.Referer())
+				}
+			}
+
+			event.Send()
+		})
+	}
+}
+
+// sampled decides by the ratio of the status class if a request gets logged.
+func sampled(sampling config.AccessLogSampling, status int) bool {
+	var ratio float64
+
+	switch status / 100 { //nolint:gomnd
+	case 1:
+		ratio = sampling.Informational
+	case 2: //nolint:gomnd
+		ratio = sampling.Success
+	case 3: //nolint:gomnd
+		ratio = sampling.Redirection
+	case 4: //nolint:gomnd
+		ratio = sampling.ClientError
+	default:
+		ratio = sampling.ServerError
+	}
+
+	if ratio >= 1 {
+		return true
+	}
+
+	return rand.Float64() < ratio //nolint:gosec
+}
+
+func remoteIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+
+	return req.RemoteAddr
+}
+
+// openSyslog connects to the local syslog or to an address like udp://host:514.
+func openSyslog(addr string) (io.WriteCloser, error) {
+	network, raddr := "", ""
+
+	if addr != "" {
+		parsed, err := url.Parse(addr)
+		if err != nil || parsed.Scheme == "" {
+			return nil, fmt.Errorf("%w: invalid syslog address %s", ErrOutput, addr)
+		}
+
+		network, raddr = parsed.Scheme, parsed.Host
+
+		if network == "unix" || network == "unixgram" {
+			raddr = parsed.Path
+		}
+	}
+
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, "errors")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return writer, nil
+}
+
+func knownField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,193 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+var (
+	// ErrPattern defines the error if an origin or path pattern is malformed.
+	ErrPattern = errors.New("invalid pattern")
+
+	// ErrCredentials defines the error if credentials are allowed for any origin.
+	ErrCredentials = errors.New("credentials require explicit origins")
+)
+
+// Validate checks the CORS policy, browsers reject credentials for the
+// wildcard origin and echoing any origin with credentials would expose the
+// responses to every site.
+func Validate(cfg *config.Config) error {
+	for _, pattern := range cfg.CORS.Paths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: %s", ErrPattern, pattern)
+		}
+	}
+
+	for _, origin := range cfg.CORS.Origins {
+		if _, err := path.Match(origin, ""); err != nil {
+			return fmt.Errorf("%w: %s", ErrPattern, origin)
+		}
+
+		if cfg.CORS.Credentials && matchAll(origin) {
+			return fmt.Errorf("%w: %s matches any origin", ErrCredentials, origin)
+		}
+	}
+
+	return nil
+}
+
+// Handler applies the configured CORS policy and answers preflight requests.
+func Handler(cfg *config.Config) func(next http.Handler) http.Handler {
+	policy := cfg.CORS
+	methods := strings.ToUpper(strings.Join(policy.Methods, ", "))
+	headers := strings.Join(policy.Headers, ", ")
+	exposed := strings.Join(policy.Exposed, ", ")
+	wildcard := len(policy.Origins) == 1 && policy.Origins[0] == "*" && !policy.Credentials
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			if len(policy.Origins) == 0 || !matchAny(policy.Paths, req.URL.Path) {
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			origin := req.Header.Get("Origin")
+			preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+
+			writer.Header().Add("Vary", "Origin")
+
+			if preflight {
+				writer.Header().Add("Vary", "Access-Control-Request-Method")
+				writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			// preflights for methods outside of the policy fail without CORS headers
+			if origin == "" || !matchOrigin(policy.Origins, origin) || (preflight && !allowMethod(policy.Methods, req.Header.Get("Access-Control-Request-Method"))) {
+				if preflight {
+					writer.WriteHeader(http.StatusNoContent)
+
+					return
+				}
+
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			if wildcard {
+				writer.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if policy.Credentials {
+				writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposed != "" {
+					writer.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
+
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			if methods != "" {
+				writer.Header().Set("Access-Control-Allow-Methods", methods)
+			}
+
+			if headers == "*" {
+				writer.Header().Set("Access-Control-Allow-Headers", req.Header.Get("Access-Control-Request-Headers"))
+			} else if headers != "" {
+				writer.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+
+			if policy.MaxAge > 0 {
+				writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+
+			writer.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func matchOrigin(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		if ok, err := path.Match(strings.ToLower(allowed), strings.ToLower(origin)); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchAll reports if the origin pattern doesn't restrict the host, e.g. "*",
+// "https://*" or "*://*.*".
+func matchAll(pattern string) bool {
+	if _, host, ok := strings.Cut(pattern, "://"); ok {
+		pattern = host
+	}
+
+	host, _, _ := strings.Cut(pattern, ":")
+
+	for i := 0; i < len(host); i++ {
+		switch host[i] {
+		case '*', '?', '.':
+			continue
+		case '[':
+			// character classes match arbitrary characters as well
+			if end := strings.IndexByte(host[i:], ']'); end > 0 {
+				i += end
+
+				continue
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func allowMethod(methods []string, method string) bool {
+	// safelisted methods don't require to be listed
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+		return true
+	}
+
+	for _, allowed := range methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
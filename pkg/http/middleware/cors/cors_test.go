@@ -0,0 +1,84 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		paths       []string
+		credentials bool
+		err         error
+	}{
+		{name: "wildcard", origins: []string{"*"}},
+		{name: "wildcard credentials", origins: []string{"*"}, credentials: true, err: ErrCredentials},
+		{name: "scheme glob credentials", origins: []string{"https://*"}, credentials: true, err: ErrCredentials},
+		{name: "any glob credentials", origins: []string{"*://*.*"}, credentials: true, err: ErrCredentials},
+		{name: "class glob credentials", origins: []string{"https://[a-z]*"}, credentials: true, err: ErrCredentials},
+		{name: "subdomain credentials", origins: []string{"https://*.example.com"}, credentials: true},
+		{name: "exact credentials", origins: []string{"https://example.com:8443"}, credentials: true},
+		{name: "malformed origin", origins: []string{"https://[example.com"}, err: ErrPattern},
+		{name: "malformed path", origins: []string{"*"}, paths: []string{"/[a"}, err: ErrPattern},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.CORS.Origins = tt.origins
+			cfg.CORS.Paths = tt.paths
+			cfg.CORS.Credentials = tt.credentials
+
+			if err := Validate(cfg); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		method string
+		allow  string
+	}{
+		{name: "allowed", origin: "https://app.example.com", method: http.MethodPut, allow: "https://app.example.com"},
+		{name: "safelisted", origin: "https://app.example.com", method: http.MethodPost, allow: "https://app.example.com"},
+		{name: "method", origin: "https://app.example.com", method: http.MethodDelete},
+		{name: "origin", origin: "https://example.org", method: http.MethodPut},
+	}
+
+	cfg := config.Load()
+	cfg.CORS.Origins = []string{"https://*.example.com"}
+	cfg.CORS.Methods = []string{"GET", "PUT"}
+
+	handler := Handler(cfg)(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("unexpected call of the next handler")
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/404.html", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("expected status 204, got %d", rec.Code)
+			}
+
+			if origin := rec.Header().Get("Access-Control-Allow-Origin"); origin != tt.allow {
+				t.Fatalf("expected allowed origin %q, got %q", tt.allow, origin)
+			}
+		})
+	}
+}
@@ -1,12 +1,54 @@
 package header
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/owncloud-ops/errors/pkg/config"
 	"github.com/owncloud-ops/errors/pkg/version"
+	"github.com/rs/zerolog/log"
 )
 
+// NoncePlaceholder gets replaced by the request nonce within the content security policy.
+const NoncePlaceholder = "{nonce}"
+
+type nonceKey struct{}
+
+// nonceReader provides the random bytes of the nonces.
+//
+//nolint:gochecknoglobals
+var nonceReader io.Reader = rand.Reader
+
+//nolint:gochecknoglobals
+var allowMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// AltSvc advertises alternative services like HTTP/3 to all requests.
+func AltSvc(set func(http.Header) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			if req.ProtoMajor < 3 { //nolint:gomnd
+				_ = set(writer.Header())
+			}
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
 // Cache writes required cache headers to all requests.
 func Cache(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
@@ -18,36 +60,84 @@ func Cache(next http.Handler) http.Handler {
 	})
 }
 
-// Options writes required option headers to all requests.
-func Options(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
-		if req.Method != http.MethodOptions {
-			next.ServeHTTP(writer, req)
-		} else {
-			writer.Header().Set("Access-Control-Allow-Origin", "*")
-			writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			writer.Header().Set("Access-Control-Allow-Headers", "authorization, origin, content-type, accept")
-			writer.Header().Set("Allow", "HEAD, GET, POST, PUT, PATCH, DELETE, OPTIONS")
-
-			writer.WriteHeader(http.StatusOK)
-		}
-	})
+// Options answers OPTIONS requests with the methods the routes accept for
+// the path, other paths get passed on to the not found handler.
+func Options(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodOptions {
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			allowed := make([]string, 0, len(allowMethods)+1)
+
+			for _, method := range allowMethods {
+				if routes.Match(chi.NewRouteContext(), method, req.URL.Path) {
+					allowed = append(allowed, method)
+				}
+			}
+
+			if len(allowed) == 0 {
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			writer.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			writer.WriteHeader(http.StatusNoContent)
+		})
+	}
 }
 
-// Secure writes required access headers to all requests.
-func Secure(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
-		writer.Header().Set("Access-Control-Allow-Origin", "*")
-		writer.Header().Set("X-Frame-Options", "DENY")
-		writer.Header().Set("X-Content-Type-Options", "nosniff")
-		writer.Header().Set("X-XSS-Protection", "1; mode=block")
+// Secure writes the configured security headers to all requests.
+//
+// A fresh nonce gets generated for every request, it replaces the {nonce}
+// placeholder of the content security policy and is available via Nonce.
+func Secure(cfg *config.Config) func(http.Handler) http.Handler {
+	security := cfg.Security
 
-		if req.TLS != nil {
-			writer.Header().Set("Strict-Transport-Security", "max-age=31536000")
-		}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			// pages would break without a nonce, that's no state to respond with
+			nonce, err := generateNonce()
+			if err != nil {
+				log.Error().
+					Err(err).
+					Msg("Failed to generate CSP nonce")
 
-		next.ServeHTTP(writer, req)
-	})
+				http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+
+			setHeader(writer, "Content-Security-Policy", strings.ReplaceAll(security.CSP, NoncePlaceholder, nonce))
+			setHeader(writer, "Referrer-Policy", security.ReferrerPolicy)
+			setHeader(writer, "Permissions-Policy", security.PermissionsPolicy)
+			setHeader(writer, "Cross-Origin-Opener-Policy", security.CrossOriginOpenerPolicy)
+			setHeader(writer, "Cross-Origin-Embedder-Policy", security.CrossOriginEmbedderPolicy)
+			setHeader(writer, "Cross-Origin-Resource-Policy", security.CrossOriginResourcePolicy)
+			setHeader(writer, "X-Frame-Options", security.FrameOptions)
+			setHeader(writer, "X-XSS-Protection", security.XSSProtection)
+			writer.Header().Set("X-Content-Type-Options", "nosniff")
+
+			if req.TLS != nil {
+				setHeader(writer, "Strict-Transport-Security", security.HSTS)
+			}
+
+			next.ServeHTTP(writer, req.WithContext(context.WithValue(req.Context(), nonceKey{}, nonce)))
+		})
+	}
+}
+
+// Nonce returns the content security policy nonce of the request.
+func Nonce(ctx context.Context) string {
+	if nonce, ok := ctx.Value(nonceKey{}).(string); ok {
+		return nonce
+	}
+
+	return ""
 }
 
 // Version writes the current API version to the headers.
@@ -58,3 +148,19 @@ func Version(next http.Handler) http.Handler {
 		next.ServeHTTP(writer, req)
 	})
 }
+
+func setHeader(writer http.ResponseWriter, key, value string) {
+	if value != "" {
+		writer.Header().Set(key, value)
+	}
+}
+
+func generateNonce() (string, error) {
+	buf := make([]byte, 16) //nolint:gomnd
+
+	if _, err := io.ReadFull(nonceReader, buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
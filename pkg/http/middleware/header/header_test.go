@@ -0,0 +1,59 @@
+package header
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+func TestSecureNonce(t *testing.T) {
+	cfg := config.Load()
+	cfg.Security.CSP = "style-src 'nonce-{nonce}'"
+
+	handler := Secure(cfg)(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = writer.Write([]byte(Nonce(req.Context())))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	nonce := rec.Body.String()
+	if rec.Code != http.StatusOK || len(nonce) != 24 {
+		t.Fatalf("expected a nonce, got %d and %q", rec.Code, nonce)
+	}
+
+	if csp := rec.Header().Get("Content-Security-Policy"); csp != "style-src 'nonce-"+nonce+"'" {
+		t.Fatalf("expected nonce within the policy, got %q", csp)
+	}
+}
+
+func TestSecureNonceFailure(t *testing.T) {
+	defer func(reader io.Reader) { nonceReader = reader }(nonceReader)
+
+	nonceReader = iotest.ErrReader(errors.New("entropy exhausted"))
+
+	cfg := config.Load()
+	cfg.Security.CSP = "style-src 'nonce-{nonce}'"
+
+	called := false
+	handler := Secure(cfg)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if called || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the request to fail, got %d", rec.Code)
+	}
+
+	if strings.Contains(rec.Header().Get("Content-Security-Policy"), "nonce-'") {
+		t.Fatal("expected no policy with an empty nonce")
+	}
+}
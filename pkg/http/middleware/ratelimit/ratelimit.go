@@ -0,0 +1,227 @@
+package ratelimit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/http/core"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/accesslog"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// KeyNone disables the rate limiting.
+	KeyNone = "none"
+
+	// KeyIP limits requests per client IP.
+	KeyIP = "ip"
+
+	// KeyHost limits requests per host of the original request.
+	KeyHost = "host"
+
+	// KeyGlobal limits all requests together.
+	KeyGlobal = "global"
+)
+
+var (
+	// ErrKey defines the error if the rate limit key is unknown.
+	ErrKey = errors.New("unknown rate limit key")
+
+	// ErrLimit defines the error if the limits of an enabled rate limit are invalid.
+	ErrLimit = errors.New("invalid rate limit")
+)
+
+type metrics interface {
+	IncrementRateLimited(key string)
+}
+
+type limiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type limiters struct {
+	mu        sync.Mutex
+	items     map[string]*limiter
+	limit     rate.Limit
+	burst     int
+	ttl       time.Duration
+	lastPurge time.Time
+}
+
+// Validate checks the rate limit configuration, the limits are only checked
+// if a key enables the rate limiting.
+func Validate(cfg *config.Config) error {
+	switch cfg.RateLimit.Key {
+	case "", KeyNone:
+		return nil
+	case KeyIP, KeyHost, KeyGlobal:
+	default:
+		return fmt.Errorf("%w: %s", ErrKey, cfg.RateLimit.Key)
+	}
+
+	switch {
+	case cfg.RateLimit.Rate <= 0:
+		return fmt.Errorf("%w: rate must be positive", ErrLimit)
+	case cfg.RateLimit.Burst < 1:
+		return fmt.Errorf("%w: burst must be at least 1", ErrLimit)
+	case cfg.RateLimit.TTL <= 0:
+		return fmt.Errorf("%w: ttl must be positive", ErrLimit)
+	}
+
+	return nil
+}
+
+// RateLimit limits the requests by the configured key with a token bucket.
+func RateLimit(cfg *config.Config, m metrics) func(next http.Handler) http.Handler {
+	if cfg.RateLimit.Key == "" || cfg.RateLimit.Key == KeyNone || cfg.RateLimit.Rate <= 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	store := &limiters{
+		items: make(map[string]*limiter),
+		limit: rate.Limit(cfg.RateLimit.Rate),
+		burst: cfg.RateLimit.Burst,
+		ttl:   cfg.RateLimit.TTL,
+	}
+
+	// pages get rendered with a placeholder which is replaced by the request nonce
+	placeholder := []byte("ratelimit-nonce-placeholder")
+
+	htmlPage, err := core.RenderErrorPage(cfg, http.StatusTooManyRequests, core.HTMLContentType, string(placeholder), nil)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("Failed to pre-render rate limit page")
+	}
+
+	jsonPage, err := core.RenderErrorPage(cfg, http.StatusTooManyRequests, core.JSONContentType, string(placeholder), nil)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("Failed to pre-render rate limit page")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			delay := store.reserve(requestKey(cfg.RateLimit.Key, req))
+
+			if delay <= 0 {
+				next.ServeHTTP(writer, req)
+
+				return
+			}
+
+			m.IncrementRateLimited(cfg.RateLimit.Key)
+
+			writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writer.Header().Set("X-Robots-Tag", "noindex")
+
+			content := htmlPage
+			format := core.HTMLContentType
+
+			if core.ClientWantFormat(req) == core.JSONContentType {
+				content = jsonPage
+				format = core.JSONContentType
+			}
+
+			if record := accesslog.FromContext(req.Context()); record != nil {
+				record.Format = core.FormatName(format)
+				record.Template = core.TemplateName(format)
+			}
+
+			// request details differ for every request and can't be pre-rendered
+			if cfg.Details.Enabled {
+				rendered, err := core.RenderErrorPage(
+					cfg,
+					http.StatusTooManyRequests,
+					format,
+					string(placeholder),
+					core.RequestDetails(cfg, req),
+				)
+				if err == nil {
+					content = rendered
+				}
+			}
+
+			if content == nil {
+				http.Error(writer, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+				return
+			}
+
+			core.SetClientFormat(writer, format)
+			writer.WriteHeader(http.StatusTooManyRequests)
+			_, _ = writer.Write(bytes.ReplaceAll(content, placeholder, []byte(header.Nonce(req.Context()))))
+		})
+	}
+}
+
+// reserve takes a token for the key and returns the delay until a token would be available.
+func (l *limiters) reserve(key string) time.Duration {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ttl > 0 && now.Sub(l.lastPurge) > l.ttl {
+		for k, item := range l.items {
+			if now.Sub(item.lastSeen) > l.ttl {
+				delete(l.items, k)
+			}
+		}
+
+		l.lastPurge = now
+	}
+
+	item, ok := l.items[key]
+	if !ok {
+		item = &limiter{
+			limiter: rate.NewLimiter(l.limit, l.burst),
+		}
+
+		l.items[key] = item
+	}
+
+	item.lastSeen = now
+
+	reservation := item.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Second
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+
+		return delay
+	}
+
+	return 0
+}
+
+func requestKey(key string, req *http.Request) string {
+	switch key {
+	case KeyIP:
+		if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			return host
+		}
+
+		return req.RemoteAddr
+	case KeyHost:
+		return core.OriginalHost(req)
+	}
+
+	return KeyGlobal
+}
@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/http/core"
+	"golang.org/x/time/rate"
+)
+
+type counter struct {
+	limited map[string]int
+}
+
+func (c *counter) IncrementRateLimited(key string) {
+	c.limited[key]++
+}
+
+func limitConfig(key string, burst int) *config.Config {
+	cfg := config.Load()
+	cfg.RateLimit.Key = key
+	cfg.RateLimit.Rate = 0.5
+	cfg.RateLimit.Burst = burst
+	cfg.RateLimit.TTL = time.Minute
+
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		rate  float64
+		burst int
+		ttl   time.Duration
+		err   error
+	}{
+		{name: "valid", key: KeyIP, rate: 10, burst: 20, ttl: time.Minute},
+		{name: "fractional rate", key: KeyHost, rate: 0.1, burst: 1, ttl: time.Minute},
+		{name: "zero rate", key: KeyHost, rate: 0, burst: 1, ttl: time.Minute, err: ErrLimit},
+		{name: "unknown key", key: "user", rate: 10, burst: 20, ttl: time.Minute, err: ErrKey},
+		{name: "negative rate", key: KeyIP, rate: -1, burst: 20, ttl: time.Minute, err: ErrLimit},
+		{name: "zero burst", key: KeyGlobal, rate: 10, burst: 0, ttl: time.Minute, err: ErrLimit},
+		{name: "zero ttl", key: KeyIP, rate: 10, burst: 20, ttl: 0, err: ErrLimit},
+		{name: "negative ttl", key: KeyIP, rate: 10, burst: 20, ttl: -time.Second, err: ErrLimit},
+		{name: "no key", key: "", rate: -1, burst: 0, ttl: 0},
+		{name: "none key", key: KeyNone, rate: -1, burst: 0, ttl: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.RateLimit.Key = tt.key
+			cfg.RateLimit.Rate = tt.rate
+			cfg.RateLimit.Burst = tt.burst
+			cfg.RateLimit.TTL = tt.ttl
+
+			if err := Validate(cfg); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		body   string
+		format string
+	}{
+		{name: "html", accept: "text/html", body: "<html", format: "text/html"},
+		{name: "json", accept: "application/json", body: `"status": "429"`, format: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &counter{limited: map[string]int{}}
+			handler := RateLimit(limitConfig(KeyGlobal, 1), m)(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusNotFound)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(core.FormatHeader, tt.accept)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("expected first request to pass, got %d", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("expected status 429, got %d", rec.Code)
+			}
+
+			// a token gets available every 2 seconds
+			if retry := rec.Header().Get("Retry-After"); retry != "2" {
+				t.Fatalf("expected Retry-After 2, got %q", retry)
+			}
+
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.format) {
+				t.Fatalf("expected %s, got %q", tt.format, rec.Header().Get("Content-Type"))
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Fatalf("expected body containing %q, got %q", tt.body, rec.Body.String())
+			}
+
+			if strings.Contains(rec.Body.String(), "ratelimit-nonce-placeholder") {
+				t.Fatal("expected nonce placeholder to be replaced")
+			}
+
+			if m.limited[KeyGlobal] != 1 {
+				t.Fatalf("expected 1 limited request, got %d", m.limited[KeyGlobal])
+			}
+		})
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		prepare func(req *http.Request, client string)
+	}{
+		{
+			name: "ip",
+			key:  KeyIP,
+			prepare: func(req *http.Request, client string) {
+				req.RemoteAddr = client + ":1234"
+			},
+		},
+		{
+			name: "host",
+			key:  KeyHost,
+			prepare: func(req *http.Request, client string) {
+				req.Header.Set("X-Forwarded-Host", client)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RateLimit(limitConfig(tt.key, 1), &counter{limited: map[string]int{}})(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusNotFound)
+			}))
+
+			for _, step := range []struct {
+				client string
+				status int
+			}{
+				{"10.0.0.1", http.StatusNotFound},
+				{"10.0.0.2", http.StatusNotFound},
+				{"10.0.0.1", http.StatusTooManyRequests},
+				{"10.0.0.2", http.StatusTooManyRequests},
+				{"10.0.0.3", http.StatusNotFound},
+			} {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				tt.prepare(req, step.client)
+
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				if rec.Code != step.status {
+					t.Fatalf("%s: expected status %d, got %d", step.client, step.status, rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestEviction(t *testing.T) {
+	store := &limiters{
+		items: make(map[string]*limiter),
+		limit: rate.Limit(0.001),
+		burst: 1,
+		ttl:   50 * time.Millisecond,
+	}
+
+	if delay := store.reserve("idle"); delay != 0 {
+		t.Fatalf("expected first request to pass, got delay %s", delay)
+	}
+
+	if delay := store.reserve("idle"); delay <= 0 {
+		t.Fatal("expected second request to be limited")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// reserving any key purges the idle ones
+	store.reserve("active")
+
+	store.mu.Lock()
+	_, ok := store.items["idle"]
+	size := len(store.items)
+	store.mu.Unlock()
+
+	if ok || size != 1 {
+		t.Fatalf("expected idle key to be evicted, got %d keys", size)
+	}
+
+	if delay := store.reserve("idle"); delay != 0 {
+		t.Fatalf("expected evicted key to start with a full bucket, got delay %s", delay)
+	}
+}
@@ -1,7 +1,6 @@
 package router
 
 import (
-	"crypto/tls"
 	"net/http"
 	"time"
 
@@ -10,54 +9,69 @@ import (
 	"github.com/owncloud-ops/errors/pkg/config"
 	errorpagesHandler "github.com/owncloud-ops/errors/pkg/http/handler/errorpage"
 	healthHandler "github.com/owncloud-ops/errors/pkg/http/handler/healthz"
+	loglevelHandler "github.com/owncloud-ops/errors/pkg/http/handler/loglevel"
 	metricsHandler "github.com/owncloud-ops/errors/pkg/http/handler/metrics"
 	"github.com/owncloud-ops/errors/pkg/http/handler/notfound"
+	readyHandler "github.com/owncloud-ops/errors/pkg/http/handler/readyz"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/accesslog"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/cors"
 	"github.com/owncloud-ops/errors/pkg/http/middleware/header"
 	"github.com/owncloud-ops/errors/pkg/http/middleware/metrics"
+	"github.com/owncloud-ops/errors/pkg/http/middleware/ratelimit"
 	"github.com/rs/zerolog/hlog"
 	"github.com/rs/zerolog/log"
 )
 
-const MiddlewareTimeout = 60 * time.Second
-
 // Load initializes the routing of the application.
 func Load(cfg *config.Config) http.Handler {
 	mux := chi.NewRouter()
 
+	// the client address is resolved first to be used by all loggers
+	mux.Use(middleware.RealIP)
 	mux.Use(hlog.NewHandler(log.Logger))
 	mux.Use(hlog.RemoteAddrHandler("ip"))
 	mux.Use(hlog.URLHandler("path"))
 	mux.Use(hlog.MethodHandler("method"))
 	mux.Use(hlog.RequestIDHandler("request_id", "Request-Id"))
 
-	mux.Use(hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
-		hlog.FromRequest(r).Debug().
-			Str("method", r.Method).
-			Str("url", r.URL.String()).
-			Int("status", status).
-			Int("size", size).
-			Dur("duration", duration).
-			Msg("")
-	}))
-
-	mux.Use(middleware.Timeout(MiddlewareTimeout))
-	mux.Use(middleware.RealIP)
+	if cfg.AccessLog.Enabled {
+		mux.Use(accesslog.Handler(cfg))
+	} else {
+		mux.Use(hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
+			hlog.FromRequest(r).Debug().
+				Str("method", r.Method).
+				Str("url", r.URL.String()).
+				Int("status", status).
+				Int("size", size).
+				Dur("duration", duration).
+				Msg("")
+		}))
+	}
+
+	if cfg.Server.HandlerTimeout > 0 {
+		mux.Use(middleware.Timeout(cfg.Server.HandlerTimeout))
+	}
+
 	mux.Use(metrics.DurationMetrics(&cfg.Metrics.Metrics))
 	mux.Use(header.Version)
 	mux.Use(header.Cache)
-	mux.Use(header.Secure)
-	mux.Use(header.Options)
+	mux.Use(header.Secure(cfg))
+	mux.Use(cors.Handler(cfg))
+	mux.Use(header.Options(mux))
+
+	limit := ratelimit.RateLimit(cfg, &cfg.Metrics.Metrics)
 
 	mux.Route(cfg.Server.Root, func(root chi.Router) {
-		root.Get("/{code}.html", errorpagesHandler.NewHandler(cfg))
+		root.With(limit).Get("/{code}.html", errorpagesHandler.NewHandler(cfg))
 		root.Get("/healthz", healthHandler.NewHandler())
+		root.Get("/readyz", readyHandler.NewHandler(cfg))
 
 		if cfg.Server.Pprof {
 			root.Mount("/debug", middleware.Profiler())
 		}
 	})
 
-	mux.NotFound(notfound.NewHandler(cfg))
+	mux.NotFound(limit(notfound.NewHandler(cfg)).ServeHTTP)
 
 	return mux
 }
@@ -66,52 +80,39 @@ func Load(cfg *config.Config) http.Handler {
 func Metrics(cfg *config.Config) http.Handler {
 	mux := chi.NewRouter()
 
+	// the client address is resolved first to be used by all loggers
+	mux.Use(middleware.RealIP)
 	mux.Use(hlog.NewHandler(log.Logger))
 	mux.Use(hlog.RemoteAddrHandler("ip"))
 	mux.Use(hlog.URLHandler("path"))
 	mux.Use(hlog.MethodHandler("method"))
 	mux.Use(hlog.RequestIDHandler("request_id", "Request-Id"))
 
-	mux.Use(middleware.Timeout(MiddlewareTimeout))
-	mux.Use(middleware.RealIP)
+	if cfg.Metrics.HandlerTimeout > 0 {
+		mux.Use(middleware.Timeout(cfg.Metrics.HandlerTimeout))
+	}
+
 	mux.Use(header.Version)
 	mux.Use(header.Cache)
-	mux.Use(header.Secure)
-	mux.Use(header.Options)
+	mux.Use(header.Secure(cfg))
+	mux.Use(header.Options(mux))
 
 	mux.Route("/", func(root chi.Router) {
 		root.Get("/metrics", metricsHandler.NewHandler(cfg))
 		root.Get("/healthz", healthHandler.NewHandler())
+		root.Get("/readyz", readyHandler.NewHandler(cfg))
+
+		// changing the level is only exposed on protected listeners, registered
+		// per method as the OPTIONS handler can't look into sub routers
+		if metricsHandler.Protected(cfg) {
+			loglevel := root.With(metricsHandler.Protect(cfg))
+			loglevel.Get("/loglevel", loglevelHandler.NewHandler())
+			loglevel.Put("/loglevel", loglevelHandler.NewHandler())
+			loglevel.Post("/loglevel", loglevelHandler.NewHandler())
+		}
 	})
 
 	mux.NotFound(notfound.NewHandler(cfg))
 
 	return mux
 }
-
-// Curves provides optionally a list of secure curves.
-func Curves(cfg *config.Config) []tls.CurveID {
-	if cfg.Server.StrictCurves {
-		return []tls.CurveID{
-			tls.CurveP521,
-			tls.CurveP384,
-			tls.CurveP256,
-		}
-	}
-
-	return nil
-}
-
-// Ciphers provides optionally a list of secure ciphers.
-func Ciphers(cfg *config.Config) []uint16 {
-	if cfg.Server.StrictCiphers {
-		return []uint16{
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/metrics"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestAccessLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "locales", "de", "html.tmpl")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("de"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		ip       string
+		locale   string
+		template string
+	}{
+		{
+			name:     "forwarded",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1", "Accept-Language": "de-CH, en;q=0.5"},
+			ip:       "203.0.113.7",
+			locale:   "de",
+			template: "locales/de/html.tmpl",
+		},
+		{
+			name:     "real ip",
+			headers:  map[string]string{"X-Real-IP": "198.51.100.2", "Accept-Language": "fr"},
+			ip:       "198.51.100.2",
+			template: "html.tmpl",
+		},
+		{
+			name:     "direct",
+			headers:  map[string]string{},
+			ip:       "192.0.2.1",
+			template: "html.tmpl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			cfg := config.Load()
+			cfg.Metrics.Metrics = metrics.NewMetrics()
+			cfg.Server.Root = "/"
+			cfg.Server.Templates = dir
+			cfg.Server.FallbackCode = http.StatusInternalServerError
+			cfg.AccessLog.Enabled = true
+			cfg.AccessLog.Writer = nopCloser{buf}
+			cfg.AccessLog.Fields = []string{"ip", "locale", "template"}
+			cfg.AccessLog.Sampling = config.AccessLogSampling{ClientError: 1}
+
+			req := httptest.NewRequest(http.MethodGet, "/404.html", nil)
+			req.RemoteAddr = "192.0.2.1:4711"
+			req.Header.Set("X-Format", "text/html")
+
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			Load(cfg).ServeHTTP(httptest.NewRecorder(), req)
+
+			entry := map[string]string{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("expected access log entry, got %q: %v", buf.String(), err)
+			}
+
+			if entry["ip"] != tt.ip || entry["locale"] != tt.locale || entry["template"] != tt.template {
+				t.Fatalf("expected %s, %q and %s, got %v", tt.ip, tt.locale, tt.template, entry)
+			}
+		})
+	}
+}
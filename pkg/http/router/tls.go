@@ -0,0 +1,297 @@
+package router
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+const (
+	// ProfileDefault uses the Go defaults with at least TLS 1.2.
+	ProfileDefault = "default"
+
+	// ProfileModern implements the Mozilla modern profile.
+	ProfileModern = "modern"
+
+	// ProfileIntermediate implements the Mozilla intermediate profile.
+	ProfileIntermediate = "intermediate"
+
+	// ProfileOld implements the Mozilla old profile.
+	ProfileOld = "old"
+)
+
+var (
+	// ErrTLSProfile defines the error if the TLS profile is unknown.
+	ErrTLSProfile = errors.New("unknown tls profile")
+
+	// ErrTLSVersion defines the error if a TLS version is unknown or the range is invalid.
+	ErrTLSVersion = errors.New("invalid tls version")
+
+	// ErrTLSCurve defines the error if a curve is unknown.
+	ErrTLSCurve = errors.New("unknown tls curve")
+
+	// ErrTLSCipher defines the error if a cipher suite is unknown or not configurable.
+	ErrTLSCipher = errors.New("invalid tls cipher")
+)
+
+// Profile defines the versions, curves and ciphers of a TLS policy.
+type Profile struct {
+	MinVersion uint16
+	MaxVersion uint16
+	Curves     []tls.CurveID
+	Ciphers    []uint16
+}
+
+//nolint:gochecknoglobals
+var profiles = map[string]Profile{
+	ProfileDefault: {
+		MinVersion: tls.VersionTLS12,
+	},
+	ProfileModern: {
+		MinVersion: tls.VersionTLS13,
+		Curves: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+	},
+	ProfileIntermediate: {
+		MinVersion: tls.VersionTLS12,
+		Curves: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+		Ciphers: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	},
+	ProfileOld: {
+		MinVersion: tls.VersionTLS10,
+		Curves: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+		Ciphers: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	},
+}
+
+// TLSConfig provides the validated TLS configuration shared by all listeners.
+func TLSConfig(cfg *config.Config) (*tls.Config, error) {
+	name := cfg.Server.TLSProfile
+	if name == "" {
+		name = ProfileDefault
+	}
+
+	profile, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTLSProfile, name)
+	}
+
+	result := &tls.Config{
+		MinVersion:       profile.MinVersion,
+		MaxVersion:       profile.MaxVersion,
+		CurvePreferences: profile.Curves,
+		CipherSuites:     profile.Ciphers,
+	}
+
+	if cfg.Server.TLSMinVersion != "" {
+		version, err := parseVersion(cfg.Server.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		result.MinVersion = version
+	}
+
+	if cfg.Server.TLSMaxVersion != "" {
+		version, err := parseVersion(cfg.Server.TLSMaxVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		result.MaxVersion = version
+	}
+
+	if result.MaxVersion != 0 && result.MaxVersion < result.MinVersion {
+		return nil, fmt.Errorf(
+			"%w: max %s is lower than min %s",
+			ErrTLSVersion,
+			tls.VersionName(result.MaxVersion),
+			tls.VersionName(result.MinVersion),
+		)
+	}
+
+	curves, err := Curves(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if curves != nil {
+		result.CurvePreferences = curves
+	}
+
+	ciphers, err := Ciphers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if ciphers != nil {
+		result.CipherSuites = ciphers
+	}
+
+	return result, nil
+}
+
+// Curves provides optionally a list of configured or strict curves.
+func Curves(cfg *config.Config) ([]tls.CurveID, error) {
+	if len(cfg.Server.TLSCurves) > 0 {
+		result := make([]tls.CurveID, 0, len(cfg.Server.TLSCurves))
+
+		for _, name := range cfg.Server.TLSCurves {
+			curve, err := parseCurve(name)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, curve)
+		}
+
+		return result, nil
+	}
+
+	if cfg.Server.StrictCurves {
+		return []tls.CurveID{
+			tls.CurveP521,
+			tls.CurveP384,
+			tls.CurveP256,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// Ciphers provides optionally a list of configured or strict ciphers.
+func Ciphers(cfg *config.Config) ([]uint16, error) {
+	if len(cfg.Server.TLSCiphers) > 0 {
+		result := make([]uint16, 0, len(cfg.Server.TLSCiphers))
+
+		for _, name := range cfg.Server.TLSCiphers {
+			cipher, err := parseCipher(name)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, cipher)
+		}
+
+		return result, nil
+	}
+
+	if cfg.Server.StrictCiphers {
+		return []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func parseVersion(name string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrTLSVersion, name)
+}
+
+func parseCurve(name string) (tls.CurveID, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "x25519":
+		return tls.X25519, nil
+	case "p256", "p-256", "curvep256", "secp256r1", "prime256v1":
+		return tls.CurveP256, nil
+	case "p384", "p-384", "curvep384", "secp384r1":
+		return tls.CurveP384, nil
+	case "p521", "p-521", "curvep521", "secp521r1":
+		return tls.CurveP521, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrTLSCurve, name)
+}
+
+// InsecureCiphers returns the names of the insecure cipher suites enabled by
+// the TLS configuration.
+func InsecureCiphers(cfg *tls.Config) []string {
+	result := make([]string, 0)
+
+	for _, suite := range tls.InsecureCipherSuites() {
+		if slices.Contains(cfg.CipherSuites, suite.ID) {
+			result = append(result, suite.Name)
+		}
+	}
+
+	return result
+}
+
+func parseCipher(name string) (uint16, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			if suite.Name != name {
+				continue
+			}
+
+			// Go always enables all TLS 1.3 suites and ignores them within the config
+			if !slices.ContainsFunc(suite.SupportedVersions, func(version uint16) bool {
+				return version < tls.VersionTLS13
+			}) {
+				return 0, fmt.Errorf("%w: %s is a TLS 1.3 suite and not configurable", ErrTLSCipher, name)
+			}
+
+			return suite.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unknown %s", ErrTLSCipher, name)
+}
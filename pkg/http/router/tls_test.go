@@ -0,0 +1,98 @@
+package router
+
+import (
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+)
+
+func TestTLSConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  config.Server
+		min     uint16
+		max     uint16
+		curves  []tls.CurveID
+		ciphers []uint16
+		err     error
+	}{
+		{name: "default", min: tls.VersionTLS12},
+		{name: "modern", server: config.Server{TLSProfile: "Modern"}, min: tls.VersionTLS13, curves: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}},
+		{name: "unknown profile", server: config.Server{TLSProfile: "strict"}, err: ErrTLSProfile},
+		{name: "versions", server: config.Server{TLSMinVersion: "TLS1.2", TLSMaxVersion: "1.3"}, min: tls.VersionTLS12, max: tls.VersionTLS13},
+		{name: "unknown version", server: config.Server{TLSMinVersion: "1.4"}, err: ErrTLSVersion},
+		{name: "ssl version", server: config.Server{TLSMinVersion: "ssl3"}, err: ErrTLSVersion},
+		{name: "inverted versions", server: config.Server{TLSMinVersion: "1.3", TLSMaxVersion: "1.2"}, err: ErrTLSVersion},
+		{name: "max below profile", server: config.Server{TLSProfile: "modern", TLSMaxVersion: "1.2"}, err: ErrTLSVersion},
+		{name: "curve aliases", server: config.Server{TLSCurves: []string{" X25519", "prime256v1", "P-384", "secp521r1"}}, min: tls.VersionTLS12, curves: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}},
+		{name: "unknown curve", server: config.Server{TLSCurves: []string{"x25519", "brainpoolP256r1"}}, err: ErrTLSCurve},
+		{name: "strict curves", server: config.Server{StrictCurves: true}, min: tls.VersionTLS12, curves: []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256}},
+		{name: "curves override strict", server: config.Server{StrictCurves: true, TLSCurves: []string{"x25519"}}, min: tls.VersionTLS12, curves: []tls.CurveID{tls.X25519}},
+		{name: "ciphers", server: config.Server{TLSCiphers: []string{"tls_ecdhe_rsa_with_aes_128_gcm_sha256", "TLS_RSA_WITH_AES_128_CBC_SHA"}}, min: tls.VersionTLS12, ciphers: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_128_CBC_SHA}},
+		{name: "unknown cipher", server: config.Server{TLSCiphers: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_NULL"}}, err: ErrTLSCipher},
+		{name: "tls 1.3 cipher", server: config.Server{TLSCiphers: []string{"TLS_AES_128_GCM_SHA256"}}, err: ErrTLSCipher},
+		{name: "openssl cipher name", server: config.Server{TLSCiphers: []string{"ECDHE-RSA-AES128-GCM-SHA256"}}, err: ErrTLSCipher},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.Server = tt.server
+
+			result, err := TLSConfig(cfg)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if result.MinVersion != tt.min || result.MaxVersion != tt.max {
+				t.Fatalf("expected versions %x-%x, got %x-%x", tt.min, tt.max, result.MinVersion, result.MaxVersion)
+			}
+
+			if !reflect.DeepEqual(result.CurvePreferences, tt.curves) {
+				t.Fatalf("expected curves %v, got %v", tt.curves, result.CurvePreferences)
+			}
+
+			if tt.ciphers != nil && !reflect.DeepEqual(result.CipherSuites, tt.ciphers) {
+				t.Fatalf("expected ciphers %v, got %v", tt.ciphers, result.CipherSuites)
+			}
+		})
+	}
+}
+
+func TestInsecureCiphers(t *testing.T) {
+	tests := []struct {
+		name   string
+		server config.Server
+		cipher string
+	}{
+		{name: "default"},
+		{name: "intermediate", server: config.Server{TLSProfile: "intermediate"}},
+		{name: "old", server: config.Server{TLSProfile: "old"}, cipher: "TLS_RSA_WITH_3DES_EDE_CBC_SHA"},
+		{name: "configured", server: config.Server{TLSCiphers: []string{"TLS_ECDHE_RSA_WITH_RC4_128_SHA"}}, cipher: "TLS_ECDHE_RSA_WITH_RC4_128_SHA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Load()
+			cfg.Server = tt.server
+
+			result, err := TLSConfig(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := InsecureCiphers(result)
+			if (tt.cipher == "" && len(got) > 0) || (tt.cipher != "" && !slices.Contains(got, tt.cipher)) {
+				t.Fatalf("expected %q, got %v", tt.cipher, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Package listener creates listeners for TCP addresses, unix sockets and systemd sockets.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// UnixPrefix defines the address prefix for unix sockets.
+	UnixPrefix = "unix://"
+
+	// SystemdPrefix defines the address prefix for systemd sockets.
+	SystemdPrefix = "systemd://"
+
+	// listenFdsStart defines the first file descriptor passed by systemd.
+	listenFdsStart = 3
+
+	// maxSystemdName defines the maximum length of socket names defined by systemd.
+	maxSystemdName = 255
+)
+
+var (
+	// ErrSystemdSocket defines the error if a systemd socket is not available.
+	ErrSystemdSocket = errors.New("systemd socket not found")
+
+	// ErrSocketMode defines the error if the socket mode can't be parsed.
+	ErrSocketMode = errors.New("invalid socket mode")
+
+	// ErrAddress defines the error if an address can't be parsed.
+	ErrAddress = errors.New("invalid address")
+)
+
+//nolint:gochecknoglobals
+var (
+	systemdOnce  sync.Once
+	systemdFiles []*os.File
+)
+
+// Listen creates a listener for a TCP address, a unix socket or a systemd socket.
+func Listen(addr, mode string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, UnixPrefix):
+		return listenUnix(strings.TrimPrefix(addr, UnixPrefix), mode)
+	case strings.HasPrefix(addr, SystemdPrefix):
+		return listenSystemd(strings.TrimPrefix(addr, SystemdPrefix))
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	return l, nil
+}
+
+// Validate checks the address and the socket mode without listening.
+func Validate(addr, mode string) error {
+	switch {
+	case strings.HasPrefix(addr, UnixPrefix):
+		if strings.TrimPrefix(addr, UnixPrefix) == "" {
+			return fmt.Errorf("%w: missing socket path in %s", ErrAddress, addr)
+		}
+
+		if mode != "" {
+			if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+				return fmt.Errorf("%w: %s", ErrSocketMode, mode)
+			}
+		}
+
+		return nil
+	case strings.HasPrefix(addr, SystemdPrefix):
+		return validateSystemd(strings.TrimPrefix(addr, SystemdPrefix))
+	}
+
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		return fmt.Errorf("%w: %s", ErrAddress, addr)
+	}
+
+	return nil
+}
+
+// IsTCP returns if the address refers to a regular TCP address.
+func IsTCP(addr string) bool {
+	return !strings.HasPrefix(addr, UnixPrefix) && !strings.HasPrefix(addr, SystemdPrefix)
+}
+
+// UnixPath returns the socket path if the address refers to a unix socket.
+func UnixPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, UnixPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(addr, UnixPrefix), true
+}
+
+func listenUnix(path, mode string) (net.Listener, error) {
+	var perm fs.FileMode
+
+	if mode != "" {
+		parsed, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSocketMode, mode)
+		}
+
+		perm = fs.FileMode(parsed)
+	}
+
+	if stat, err := os.Stat(path); err == nil && stat.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			_ = l.Close()
+
+			return nil, fmt.Errorf("failed to chmod socket %s: %w", path, err)
+		}
+	}
+
+	return l, nil
+}
+
+// listenSystemd picks a socket passed via LISTEN_FDS, the name can be empty
+// for the first socket, an index or a name defined by LISTEN_FDNAMES.
+func listenSystemd(name string) (net.Listener, error) {
+	systemdOnce.Do(loadSystemdFiles)
+
+	var file *os.File
+
+	if index, err := strconv.Atoi(name); name == "" || err == nil {
+		if index >= 0 && index < len(systemdFiles) {
+			file = systemdFiles[index]
+		}
+	} else {
+		for _, f := range systemdFiles {
+			if f.Name() == name {
+				file = f
+
+				break
+			}
+		}
+	}
+
+	if file == nil {
+		return nil, fmt.Errorf("%w: %q", ErrSystemdSocket, name)
+	}
+
+	l, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd socket %q: %w", name, err)
+	}
+
+	return l, nil
+}
+
+// validateSystemd checks the syntax of the socket name or index and if the
+// socket has been passed by systemd.
+func validateSystemd(name string) error {
+	index, err := strconv.Atoi(name)
+
+	switch {
+	case name == "":
+		index = 0
+	case err == nil:
+		if index < 0 {
+			return fmt.Errorf("%w: negative systemd socket index %s", ErrAddress, name)
+		}
+	default:
+		if len(name) > maxSystemdName || strings.ContainsFunc(name, func(r rune) bool {
+			return r <= ' ' || r > '~' || r == ':'
+		}) {
+			return fmt.Errorf("%w: invalid systemd socket name %q", ErrAddress, name)
+		}
+
+		index = -1
+	}
+
+	names, err := systemdNames()
+	if err != nil {
+		return err
+	}
+
+	if index >= len(names) || (index < 0 && !slices.Contains(names, name)) {
+		return fmt.Errorf("%w: %q is not within the %d passed sockets %s", ErrSystemdSocket, name, len(names), strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// systemdNames returns the names of the sockets passed by systemd without
+// consuming them.
+func systemdNames() ([]string, error) {
+	if len(systemdFiles) > 0 {
+		names := make([]string, 0, len(systemdFiles))
+
+		for _, file := range systemdFiles {
+			names = append(names, file.Name())
+		}
+
+		return names, nil
+	}
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("%w: not started by systemd socket activation, LISTEN_PID does not match the process", ErrSystemdSocket)
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("%w: not started by systemd socket activation, LISTEN_FDS is not set", ErrSystemdSocket)
+	}
+
+	passed := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	names := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFdsStart+i)
+
+		if i < len(passed) && passed[i] != "" {
+			name = passed[i]
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func loadSystemdFiles() {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	names, err := systemdNames()
+	if err != nil {
+		return
+	}
+
+	for i, name := range names {
+		systemdFiles = append(systemdFiles, os.NewFile(uintptr(listenFdsStart+i), name))
+	}
+}
@@ -0,0 +1,187 @@
+package listener
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "2")
+	t.Setenv("LISTEN_FDNAMES", "http:metrics")
+
+	tests := []struct {
+		name string
+		addr string
+		mode string
+		err  error
+	}{
+		{name: "tcp", addr: "0.0.0.0:8080"},
+		{name: "tcp host", addr: "localhost:http"},
+		{name: "tcp ipv6", addr: "[::1]:8080"},
+		{name: "tcp missing port", addr: "localhost", err: ErrAddress},
+		{name: "tcp empty port", addr: "localhost:", err: ErrAddress},
+		{name: "unix", addr: "unix:///run/errors.sock", mode: "0660"},
+		{name: "unix without mode", addr: "unix:///run/errors.sock"},
+		{name: "unix relative", addr: "unix://errors.sock", mode: "600"},
+		{name: "unix missing path", addr: "unix://", err: ErrAddress},
+		{name: "unix decimal mode", addr: "unix:///run/errors.sock", mode: "0990", err: ErrSocketMode},
+		{name: "unix symbolic mode", addr: "unix:///run/errors.sock", mode: "rw-rw----", err: ErrSocketMode},
+		{name: "systemd first", addr: "systemd://"},
+		{name: "systemd index", addr: "systemd://1"},
+		{name: "systemd name", addr: "systemd://metrics"},
+		{name: "systemd index missing", addr: "systemd://2", err: ErrSystemdSocket},
+		{name: "systemd negative index", addr: "systemd://-1", err: ErrAddress},
+		{name: "systemd name missing", addr: "systemd://https", err: ErrSystemdSocket},
+		{name: "systemd name colon", addr: "systemd://http:metrics", err: ErrAddress},
+		{name: "systemd name space", addr: "systemd://my socket", err: ErrAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.addr, tt.mode); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateWithoutSystemd(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  string
+		fds  string
+	}{
+		{name: "unset"},
+		{name: "other process", pid: strconv.Itoa(os.Getpid() + 1), fds: "1"},
+		{name: "no sockets", pid: strconv.Itoa(os.Getpid()), fds: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.pid)
+			t.Setenv("LISTEN_FDS", tt.fds)
+
+			if err := Validate("systemd://", ""); !errors.Is(err, ErrSystemdSocket) {
+				t.Fatalf("expected %v, got %v", ErrSystemdSocket, err)
+			}
+		})
+	}
+}
+
+func TestUnixPath(t *testing.T) {
+	tests := []struct {
+		addr string
+		path string
+		unix bool
+		tcp  bool
+	}{
+		{addr: "unix:///run/errors.sock", path: "/run/errors.sock", unix: true},
+		{addr: "unix://errors.sock", path: "errors.sock", unix: true},
+		{addr: "systemd://http"},
+		{addr: "127.0.0.1:8080", tcp: true},
+	}
+
+	for _, tt := range tests {
+		path, ok := UnixPath(tt.addr)
+
+		if path != tt.path || ok != tt.unix {
+			t.Errorf("%s: expected %q and %v, got %q and %v", tt.addr, tt.path, tt.unix, path, ok)
+		}
+
+		if IsTCP(tt.addr) != tt.tcp {
+			t.Errorf("%s: expected tcp %v", tt.addr, tt.tcp)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		perm fs.FileMode
+	}{
+		{name: "group", mode: "0660", perm: 0o660},
+		{name: "owner", mode: "600", perm: 0o600},
+		{name: "world", mode: "0666", perm: 0o666},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "errors.sock")
+
+			l, err := Listen(UnixPrefix+path, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer l.Close()
+
+			stat, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stat.Mode().Type() != fs.ModeSocket || stat.Mode().Perm() != tt.perm {
+				t.Fatalf("expected socket with %s, got %s", tt.perm, stat.Mode())
+			}
+
+			conn, err := net.Dial("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_ = conn.Close()
+		})
+	}
+}
+
+func TestListenUnixStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.sock")
+
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keep the socket file like a crashed process would
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	l, err := Listen(UnixPrefix+path, "")
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced: %v", err)
+	}
+
+	_ = l.Close()
+}
+
+func TestListenUnixNoSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.sock")
+
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// regular files are never removed
+	if _, err := Listen(UnixPrefix+path, ""); err == nil {
+		t.Fatal("expected listening on a regular file to fail")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected regular file to be kept: %v", err)
+	}
+}
+
+func TestListenUnixMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.sock")
+
+	if _, err := Listen(UnixPrefix+path, "rw"); !errors.Is(err, ErrSocketMode) {
+		t.Fatalf("expected %v, got %v", ErrSocketMode, err)
+	}
+}
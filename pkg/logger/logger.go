@@ -0,0 +1,155 @@
+// Package logger configures the application log and changes its level at runtime.
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// FormatJSON writes every entry as JSON object.
+	FormatJSON = "json"
+
+	// FormatConsole writes human readable entries.
+	FormatConsole = "console"
+
+	// FormatLogfmt writes entries as key=value pairs.
+	FormatLogfmt = "logfmt"
+)
+
+var (
+	// ErrLevel defines the error if a log level is unknown.
+	ErrLevel = errors.New("unknown log level")
+
+	// ErrFormat defines the error if a log format is unknown.
+	ErrFormat = errors.New("unknown log format")
+)
+
+// levels defines the supported levels ordered from verbose to quiet.
+//
+//nolint:gochecknoglobals
+var levels = []zerolog.Level{
+	zerolog.DebugLevel,
+	zerolog.InfoLevel,
+	zerolog.WarnLevel,
+	zerolog.ErrorLevel,
+	zerolog.FatalLevel,
+	zerolog.PanicLevel,
+}
+
+// ParseLevel parses the name of a supported log level.
+func ParseLevel(name string) (zerolog.Level, error) {
+	for _, level := range levels {
+		if strings.ToLower(strings.TrimSpace(name)) == level.String() {
+			return level, nil
+		}
+	}
+
+	return zerolog.NoLevel, fmt.Errorf("%w: %s", ErrLevel, name)
+}
+
+// Step changes the global level by the given number of steps, negative steps
+// make the log more verbose. The level stays within the supported levels.
+func Step(steps int) zerolog.Level {
+	current := 0
+
+	for i, level := range levels {
+		if level == zerolog.GlobalLevel() {
+			current = i
+		}
+	}
+
+	next := min(max(current+steps, 0), len(levels)-1)
+	zerolog.SetGlobalLevel(levels[next])
+
+	return levels[next]
+}
+
+// TimeFormat translates the timestamp option to a zerolog time format, known
+// names are rfc3339, rfc3339nano, unix, unixms, unixmicro and unixnano, any
+// other value is used as Go time layout.
+func TimeFormat(name string) string {
+	switch strings.ToLower(name) {
+	case "", "rfc3339":
+		return time.RFC3339
+	case "rfc3339nano":
+		return time.RFC3339Nano
+	case "unix":
+		return zerolog.TimeFormatUnix
+	case "unixms":
+		return zerolog.TimeFormatUnixMs
+	case "unixmicro":
+		return zerolog.TimeFormatUnixMicro
+	case "unixnano":
+		return zerolog.TimeFormatUnixNano
+	}
+
+	return name
+}
+
+// Writer wraps the output for the requested format.
+func Writer(out io.Writer, format string, color bool) (io.Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return out, nil
+	case FormatConsole:
+		return zerolog.ConsoleWriter{
+			Out:     out,
+			NoColor: !color,
+		}, nil
+	case FormatLogfmt:
+		return zerolog.ConsoleWriter{
+			Out:                 out,
+			NoColor:             true,
+			PartsOrder:          []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.CallerFieldName, zerolog.MessageFieldName},
+			FormatTimestamp:     logfmtPart(zerolog.TimestampFieldName),
+			FormatLevel:         logfmtPart(zerolog.LevelFieldName),
+			FormatCaller:        logfmtPart(zerolog.CallerFieldName),
+			FormatMessage:       logfmtPart("msg"),
+			FormatFieldName:     logfmtName,
+			FormatErrFieldName:  logfmtName,
+			FormatErrFieldValue: logfmtValue,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrFormat, format)
+}
+
+func logfmtPart(name string) zerolog.Formatter {
+	return func(i interface{}) string {
+		if i == nil {
+			return ""
+		}
+
+		value := fmt.Sprint(i)
+
+		if value == "" {
+			return ""
+		}
+
+		return name + "=" + quote(value)
+	}
+}
+
+func logfmtName(i interface{}) string {
+	return fmt.Sprint(i) + "="
+}
+
+// logfmtValue keeps values as they are, strings are already quoted if required.
+func logfmtValue(i interface{}) string {
+	return fmt.Sprint(i)
+}
+
+func quote(value string) string {
+	if strings.ContainsAny(value, " =\"\\") || strconv.Quote(value) != `"`+value+`"` {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
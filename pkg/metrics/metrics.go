@@ -9,6 +9,12 @@ import (
 type Metrics struct {
 	total    prometheus.Counter
 	duration prometheus.Histogram
+	expiry   *prometheus.GaugeVec
+	limited  *prometheus.CounterVec
+	rewrites *prometheus.CounterVec
+	redirect *prometheus.CounterVec
+	upstream *prometheus.CounterVec
+	snapshot *prometheus.CounterVec
 }
 
 // NewMetrics creates new Metrics collector.
@@ -29,6 +35,42 @@ func NewMetrics() Metrics {
 			Help:      "histogram of the time (in seconds) each request took",
 			Buckets:   append([]float64{.001, .003}, prometheus.DefBuckets...),
 		}),
+		expiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tls",
+			Name:      "certificate_expiry_timestamp_seconds",
+			Help:      "unix timestamp when the currently served certificate expires",
+		}, []string{"name"}),
+		limited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "rate_limited_total",
+			Help:      "counter of http requests rejected by the rate limit",
+		}, []string{"key"}),
+		rewrites: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "rewritten_total",
+			Help:      "counter of error pages rewritten by a rule",
+		}, []string{"rule", "original_code", "code"}),
+		redirect: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "redirected_total",
+			Help:      "counter of errors answered by a redirect",
+		}, []string{"redirect", "code"}),
+		upstream: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "upstream_total",
+			Help:      "counter of error pages requested from upstreams by result",
+		}, []string{"upstream", "result"}),
+		snapshot: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "snapshot_total",
+			Help:      "counter of snapshot lookups for failing upstreams by result",
+		}, []string{"result"}),
 	}
 }
 
@@ -38,11 +80,61 @@ func (w *Metrics) IncrementTotalRequests() { w.total.Inc() }
 // ObserveRequestDuration observer requests duration histogram.
 func (w *Metrics) ObserveRequestDuration(t time.Duration) { w.duration.Observe(t.Seconds()) }
 
+// IncrementRateLimited increments the rate limited requests counter.
+func (w *Metrics) IncrementRateLimited(key string) { w.limited.WithLabelValues(key).Inc() }
+
+// IncrementRewritten increments the rewritten error pages counter.
+func (w *Metrics) IncrementRewritten(rule, original, code string) {
+	w.rewrites.WithLabelValues(rule, original, code).Inc()
+}
+
+// IncrementRedirected increments the redirected errors counter.
+func (w *Metrics) IncrementRedirected(redirect, code string) {
+	w.redirect.WithLabelValues(redirect, code).Inc()
+}
+
+// IncrementUpstream increments the upstream error pages counter.
+func (w *Metrics) IncrementUpstream(upstream, result string) {
+	w.upstream.WithLabelValues(upstream, result).Inc()
+}
+
+// IncrementSnapshot increments the snapshot lookups counter.
+func (w *Metrics) IncrementSnapshot(result string) { w.snapshot.WithLabelValues(result).Inc() }
+
+// SetCertificateExpiry sets the expiry timestamp of the named certificate.
+func (w *Metrics) SetCertificateExpiry(name string, t time.Time) {
+	w.expiry.WithLabelValues(name).Set(float64(t.Unix()))
+}
+
 // Register metrics with registerer.
 func (w *Metrics) Register(reg prometheus.Registerer) error {
 	if err := reg.Register(w.total); err != nil {
 		return err
 	}
 
-	return reg.Register(w.duration)
+	if err := reg.Register(w.duration); err != nil {
+		return err
+	}
+
+	if err := reg.Register(w.expiry); err != nil {
+		return err
+	}
+
+	if err := reg.Register(w.limited); err != nil {
+		return err
+	}
+
+	if err := reg.Register(w.rewrites); err != nil {
+		return err
+	}
+
+	if err := reg.Register(w.redirect); err != nil {
+		return err
+	}
+
+	if err := reg.Register(w.upstream); err != nil {
+		return err
+	}
+
+	return reg.Register(w.snapshot)
 }
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNamespace(t *testing.T) {
+	m := NewMetrics()
+	m.SetCertificateExpiry("server", time.Now())
+	m.IncrementRateLimited("ip")
+	m.IncrementRewritten("rule", "404", "503")
+	m.IncrementRedirected("login", "401")
+	m.IncrementUpstream("team", "hit")
+	m.IncrementSnapshot("hit")
+
+	reg := prometheus.NewRegistry()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "http_") {
+			t.Errorf("expected %s within the http namespace", family.GetName())
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package snapshot keeps last known good copies of pages on disk.
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrURL defines the error if a snapshot URL is invalid.
+	ErrURL = errors.New("invalid snapshot url")
+
+	// ErrStatus defines the error if a page answered without success.
+	ErrStatus = errors.New("unexpected status")
+
+	// ErrContentType defines the error if a page is not HTML.
+	ErrContentType = errors.New("unsupported content type")
+
+	// ErrSize defines the error if a page exceeds the maximum size.
+	ErrSize = errors.New("page too large")
+)
+
+// PruneInterval defines how often Enforce checks the maximum size.
+const PruneInterval = time.Minute
+
+// Page defines a snapshot of a page.
+type Page struct {
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Time        time.Time `json:"time"`
+	Size        int64     `json:"size"`
+	Body        []byte    `json:"-"`
+}
+
+// Store keeps snapshots within a directory, the total size gets bounded by
+// pruning the oldest snapshots.
+type Store struct {
+	dir         string
+	maxSize     int64
+	maxPageSize int64
+}
+
+// NewStore creates a store for the directory.
+func NewStore(dir string, maxSize, maxPageSize int64) *Store {
+	return &Store{
+		dir:         dir,
+		maxSize:     maxSize,
+		maxPageSize: maxPageSize,
+	}
+}
+
+// Key returns the key of a page by host and path, ports, queries and
+// fragments are ignored.
+func Key(host, path string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.ToLower(host) + path
+}
+
+// ParseURL validates a snapshot URL and returns its key.
+func ParseURL(raw string) (string, error) {
+	uri, err := url.Parse(raw)
+	if err != nil || (uri.Scheme != "http" && uri.Scheme != "https") || uri.Host == "" {
+		return "", fmt.Errorf("%w: %s", ErrURL, raw)
+	}
+
+	return Key(uri.Host, uri.EscapedPath()), nil
+}
+
+// Load reads the snapshot of the host and path.
+func (s *Store) Load(host, path string) (*Page, error) {
+	name := s.name(Key(host, path))
+
+	meta, err := os.ReadFile(name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	page := &Page{}
+
+	if err := json.Unmarshal(meta, page); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	file, err := os.Open(name + ".html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	defer file.Close()
+
+	if page.Size > s.maxPageSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrSize, s.maxPageSize)
+	}
+
+	page.Body, err = io.ReadAll(io.LimitReader(file, s.maxPageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	if int64(len(page.Body)) > s.maxPageSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrSize, s.maxPageSize)
+	}
+
+	return page, nil
+}
+
+// Crawl fetches the URL and stores the page if it has been successful.
+func (s *Store) Crawl(ctx context.Context, client *http.Client, raw string) (*Page, error) {
+	key, err := ParseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request page: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrStatus, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != "text/html" {
+		return nil, fmt.Errorf("%w: %s", ErrContentType, contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.maxPageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page: %w", err)
+	}
+
+	if int64(len(body)) > s.maxPageSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrSize, s.maxPageSize)
+	}
+
+	page := &Page{
+		URL:         raw,
+		ContentType: contentType,
+		Time:        time.Now().UTC(),
+		Size:        int64(len(body)),
+		Body:        body,
+	}
+
+	if err := s.save(key, page); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Prune removes the oldest snapshots until the total size fits into the
+// maximum size, it returns the number of removed snapshots.
+func (s *Store) Prune() (int, error) {
+	metas, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	type stored struct {
+		name string
+		page Page
+	}
+
+	pages := make([]stored, 0, len(metas))
+	total := int64(0)
+
+	for _, meta := range metas {
+		content, err := os.ReadFile(meta)
+		if os.IsNotExist(err) {
+			// pruned concurrently by another process
+			continue
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		item := stored{
+			name: strings.TrimSuffix(meta, ".json"),
+		}
+
+		if err := json.Unmarshal(content, &item.page); err != nil {
+			return 0, fmt.Errorf("failed to parse snapshot %s: %w", meta, err)
+		}
+
+		pages = append(pages, item)
+		total += item.page.Size
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].page.Time.Before(pages[j].page.Time)
+	})
+
+	removed := 0
+
+	for _, item := range pages {
+		if total <= s.maxSize {
+			break
+		}
+
+		if err := remove(item.name); err != nil {
+			return removed, err
+		}
+
+		total -= item.page.Size
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Enforce prunes the snapshots right away and then within the interval until
+// the context gets canceled, snapshots may be written by other processes
+// sharing the directory.
+func (s *Store) Enforce(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if removed, err := s.Prune(); err != nil {
+			log.Warn().
+				Err(err).
+				Str("dir", s.dir).
+				Msg("Failed to prune snapshots")
+		} else if removed > 0 {
+			log.Info().
+				Int("removed", removed).
+				Str("dir", s.dir).
+				Msg("Pruned oldest snapshots")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// save writes the body before the metadata, both get replaced atomically.
+func (s *Store) save(key string, page *Page) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil { //nolint:gomnd
+		return fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+
+	meta, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	name := s.name(key)
+
+	if err := writeFile(name+".html", page.Body); err != nil {
+		return err
+	}
+
+	return writeFile(name+".json", meta)
+}
+
+func (s *Store) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+func writeFile(name string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	// snapshots are public pages which may be read by another user
+	if err := tmp.Chmod(0o644); err != nil { //nolint:gomnd
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func remove(name string) error {
+	for _, file := range []string{name + ".json", name + ".html"} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
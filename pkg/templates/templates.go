@@ -2,6 +2,7 @@ package templates
 
 import (
 	"embed"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io/fs"
@@ -10,15 +11,28 @@ import (
 	"strings"
 
 	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/configmap"
 	"github.com/rs/zerolog/log"
 )
 
 //go:embed dist/*
 var embeddedTemplates embed.FS
 
-// Load initializes the template files.
+// Funcs defines the functions available within templates, the values get
+// replaced by request specific implementations before execution.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"cspNonce": func() string {
+			return ""
+		},
+		"json": jsonValue,
+	}
+}
+
+// Load initializes the template files, custom templates override the builtin
+// ones of the same name.
 func Load(cfg *config.Config) *template.Template {
-	tpls := template.New("")
+	tpls := template.New("").Funcs(Funcs())
 
 	err := fs.WalkDir(embeddedTemplates, ".", func(name string, dir fs.DirEntry, err error) error {
 		if err != nil {
@@ -43,7 +57,7 @@ func Load(cfg *config.Config) *template.Template {
 
 		_, _ = tpls.New(
 			strings.TrimPrefix(
-				dir.Name(),
+				name,
 				"dist/",
 			),
 		).Parse(
@@ -58,7 +72,6 @@ func Load(cfg *config.Config) *template.Template {
 			Msg("Failed to parse builtin templates")
 	}
 
-	//nolint:nestif
 	if cfg.Server.Templates != "" {
 		if stat, err := os.Stat(cfg.Server.Templates); os.IsNotExist(err) || !stat.IsDir() {
 			log.Warn().
@@ -68,48 +81,55 @@ func Load(cfg *config.Config) *template.Template {
 			return tpls
 		}
 
-		err := filepath.Walk(cfg.Server.Templates, func(name string, dir fs.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		if err := loadCustom(tpls, cfg.Server.Templates); err != nil {
+			log.Warn().
+				Err(err).
+				Msg("Failed to parse custom templates")
+		}
+	}
 
-			if dir.IsDir() {
-				return nil
-			}
+	return tpls
+}
 
-			if forbiddenExtension(filepath.Ext(dir.Name())) {
-				return nil
-			}
+// loadCustom parses the templates of the directory, nested templates are named
+// by their relative path like themes/dark/html.tmpl to avoid collisions.
+func loadCustom(tpls *template.Template, dir string) error {
+	files, err := configmap.Walk(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list custom template files: %w", err)
+	}
 
-			content, err := os.ReadFile(
-				name,
-			)
-			if err != nil {
-				return fmt.Errorf("failed to read custom template file: %w", err)
-			}
-
-			_, _ = tpls.New(
-				strings.TrimPrefix(
-					strings.TrimPrefix(
-						dir.Name(),
-						cfg.Server.Templates,
-					),
-					"/",
-				),
-			).Parse(
-				string(content),
-			)
+	for _, file := range files {
+		if forbiddenExtension(filepath.Ext(file.Name)) {
+			continue
+		}
 
-			return nil
-		})
+		content, err := os.ReadFile(
+			file.Path,
+		)
 		if err != nil {
-			log.Warn().
-				Err(err).
-				Msg("Failed to parse custom templates")
+			return fmt.Errorf("failed to read custom template file: %w", err)
 		}
+
+		_, _ = tpls.New(
+			file.Name,
+		).Parse(
+			string(content),
+		)
 	}
 
-	return tpls
+	return nil
+}
+
+// jsonValue encodes a value as JSON, the encoder escapes HTML characters so
+// the result is safe to embed unescaped.
+func jsonValue(v interface{}) template.HTML {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return template.HTML("null")
+	}
+
+	return template.HTML(content) //nolint:gosec
 }
 
 func forbiddenExtension(ext string) bool {
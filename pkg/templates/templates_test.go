@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owncloud-ops/errors/pkg/config"
+	"github.com/owncloud-ops/errors/pkg/configmap"
+)
+
+// revision creates a config map revision like the kubelet does and points
+// the ..data symlink to it with an atomic rename.
+func revision(t *testing.T, dir, name string, files map[string]string) {
+	t.Helper()
+
+	for file, content := range files {
+		path := filepath.Join(dir, name, file)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+
+	if err := os.Symlink(name, tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, configmap.DataDir)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func render(t *testing.T, cfg *config.Config, name string) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	if err := Load(cfg).ExecuteTemplate(buf, name, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestLoadConfigMapSwap(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Load()
+	cfg.Server.Templates = dir
+
+	revision(t, dir, "..2024_01_01_00_00_00.1", map[string]string{
+		"html.tmpl":              "first",
+		"themes/dark/html.tmpl":  "first dark",
+		"locales/de/banner.tmpl": "first banner",
+	})
+
+	// the keys of a config map are linked to the ..data directory
+	for _, key := range []string{"html.tmpl", "themes"} {
+		if err := os.Symlink(filepath.Join(configmap.DataDir, key), filepath.Join(dir, key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := render(t, cfg, "html.tmpl"); got != "first" {
+		t.Fatalf("expected first revision, got %q", got)
+	}
+
+	if got := render(t, cfg, "themes/dark/html.tmpl"); got != "first dark" {
+		t.Fatalf("expected first revision of the theme, got %q", got)
+	}
+
+	if got := render(t, cfg, "locales/de/banner.tmpl"); got != "first banner" {
+		t.Fatalf("expected first revision of the locale, got %q", got)
+	}
+
+	revision(t, dir, "..2024_01_02_00_00_00.2", map[string]string{
+		"html.tmpl":             "second",
+		"themes/dark/html.tmpl": "second dark",
+	})
+
+	if err := os.RemoveAll(filepath.Join(dir, "..2024_01_01_00_00_00.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := render(t, cfg, "html.tmpl"); got != "second" {
+		t.Fatalf("expected second revision, got %q", got)
+	}
+
+	if got := render(t, cfg, "themes/dark/html.tmpl"); got != "second dark" {
+		t.Fatalf("expected second revision of the theme, got %q", got)
+	}
+
+	// files are read from the revision, keys removed by it are gone
+	if Load(cfg).Lookup("locales/de/banner.tmpl") != nil {
+		t.Fatal("expected removed template of the first revision to be gone")
+	}
+}
@@ -0,0 +1,150 @@
+// Package tokens provides named secrets which can be compared in constant time.
+package tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/owncloud-ops/errors/pkg/watch"
+	"github.com/rs/zerolog/log"
+)
+
+// Store holds named tokens from the configuration and from files.
+type Store struct {
+	static  map[string]string
+	files   []string
+	current atomic.Pointer[map[string][sha256.Size]byte]
+}
+
+// NewStore creates a store and loads the initial tokens.
+//
+// Every file defines a token named by its basename, for directories every
+// visible file inside gets loaded, which matches mounted Kubernetes secrets.
+func NewStore(static map[string]string, files []string) (*Store, error) {
+	s := &Store{
+		static: static,
+		files:  files,
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the token files again, the previous tokens are kept if it fails.
+func (s *Store) Reload() error {
+	result := make(map[string][sha256.Size]byte, len(s.static))
+
+	for name, token := range s.static {
+		if token != "" {
+			result[name] = sha256.Sum256([]byte(token))
+		}
+	}
+
+	for _, file := range s.files {
+		stat, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to read token file: %w", err)
+		}
+
+		if !stat.IsDir() {
+			if err := readToken(result, file); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			return fmt.Errorf("failed to read token dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			path := filepath.Join(file, entry.Name())
+
+			if stat, err := os.Stat(path); err != nil || stat.IsDir() {
+				continue
+			}
+
+			if err := readToken(result, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.current.Store(&result)
+
+	return nil
+}
+
+// Watch reloads the token files on changes until the context gets canceled.
+func (s *Store) Watch(ctx context.Context) error {
+	if len(s.files) == 0 {
+		<-ctx.Done()
+
+		return nil
+	}
+
+	return watch.Watch(ctx, s.files, func() {
+		if err := s.Reload(); err != nil {
+			log.Error().
+				Err(err).
+				Strs("files", s.files).
+				Msg("Failed to reload tokens, keeping previous ones")
+
+			return
+		}
+
+		log.Info().
+			Strs("files", s.files).
+			Msg("Reloaded tokens")
+	})
+}
+
+// Match compares the secret with all tokens and returns the name of the match.
+func (s *Store) Match(secret string) (string, bool) {
+	hash := sha256.Sum256([]byte(secret))
+	result := ""
+
+	for name, token := range *s.current.Load() {
+		if subtle.ConstantTimeCompare(hash[:], token[:]) == 1 {
+			result = name
+		}
+	}
+
+	return result, result != ""
+}
+
+// MatchNamed compares the secret with the token of the given name.
+func (s *Store) MatchNamed(name, secret string) bool {
+	hash := sha256.Sum256([]byte(secret))
+	token, ok := (*s.current.Load())[name]
+
+	return subtle.ConstantTimeCompare(hash[:], token[:]) == 1 && ok
+}
+
+func readToken(result map[string][sha256.Size]byte, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	if token := strings.TrimSpace(string(content)); token != "" {
+		result[filepath.Base(path)] = sha256.Sum256([]byte(token))
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeToken(t *testing.T, path, token string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	writeToken(t, filepath.Join(dir, "grafana"), "file-secret\n")
+	writeToken(t, filepath.Join(dir, ".hidden"), "hidden-secret")
+
+	store, err := NewStore(map[string]string{"prometheus": "static-secret", "empty": ""}, []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+		ok     bool
+	}{
+		{name: "static", secret: "static-secret", want: "prometheus", ok: true},
+		{name: "file", secret: "file-secret", want: "grafana", ok: true},
+		{name: "wrong", secret: "wrong-secret"},
+		{name: "prefix", secret: "static"},
+		{name: "whitespace", secret: "file-secret\n"},
+		{name: "empty", secret: ""},
+		{name: "hidden", secret: "hidden-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ok := store.Match(tt.secret)
+
+			if name != tt.want || ok != tt.ok {
+				t.Fatalf("expected %q and %v, got %q and %v", tt.want, tt.ok, name, ok)
+			}
+		})
+	}
+}
+
+func TestMatchNamed(t *testing.T) {
+	store, err := NewStore(map[string]string{"prometheus": "secret", "grafana": "other", "empty": ""}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		user   string
+		secret string
+		ok     bool
+	}{
+		{name: "match", user: "prometheus", secret: "secret", ok: true},
+		{name: "wrong secret", user: "prometheus", secret: "other"},
+		{name: "wrong name", user: "grafana", secret: "secret"},
+		{name: "unknown name", user: "unknown", secret: "secret"},
+		{name: "empty token", user: "empty", secret: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := store.MatchNamed(tt.user, tt.secret); ok != tt.ok {
+				t.Fatalf("expected %v, got %v", tt.ok, ok)
+			}
+		})
+	}
+}
+
+func TestReloadRotated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prometheus")
+	writeToken(t, path, "old-secret")
+
+	store, err := NewStore(nil, []string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeToken(t, path, "new-secret")
+
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Match("old-secret"); ok {
+		t.Fatal("expected rotated token to be rejected")
+	}
+
+	if name, ok := store.Match("new-secret"); !ok || name != "prometheus" {
+		t.Fatalf("expected new token to match, got %q", name)
+	}
+
+	// failed reloads keep the previous tokens
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Reload(); err == nil {
+		t.Fatal("expected reload of a missing file to fail")
+	}
+
+	if _, ok := store.Match("new-secret"); !ok {
+		t.Fatal("expected previous token to be kept")
+	}
+}
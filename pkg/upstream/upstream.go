@@ -0,0 +1,163 @@
+// Package upstream fetches custom error pages from upstreams and caches them.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrStatus defines the error if the upstream answered without success.
+	ErrStatus = errors.New("unexpected upstream status")
+
+	// ErrSize defines the error if the upstream page exceeds the maximum size.
+	ErrSize = errors.New("upstream page too large")
+)
+
+// Page defines a page fetched from an upstream.
+type Page struct {
+	ContentType string
+	Body        []byte
+}
+
+type item struct {
+	page    *Page
+	err     error
+	expires time.Time
+}
+
+// Cache fetches pages from upstreams, successful responses are kept for the
+// TTL and failures for the failure TTL to not wait for a broken upstream on
+// every request.
+type Cache struct {
+	client     *http.Client
+	ttl        time.Duration
+	failureTTL time.Duration
+	maxSize    int64
+
+	mu        sync.Mutex
+	items     map[string]*item
+	lastPurge time.Time
+	group     singleflight.Group
+}
+
+// NewCache creates a cache which fetches pages within the timeout.
+func NewCache(timeout, ttl, failureTTL time.Duration, maxSize int64) *Cache {
+	return &Cache{
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		ttl:        ttl,
+		failureTTL: failureTTL,
+		maxSize:    maxSize,
+		items:      make(map[string]*item),
+	}
+}
+
+// Fetch returns the page of the URL for the accepted content type, the
+// boolean reports if the page or the failure have been taken from the cache.
+// Concurrent requests for the same page share a single upstream request.
+func (c *Cache) Fetch(ctx context.Context, url, accept string) (*Page, bool, error) {
+	key := accept + " " + url
+
+	c.mu.Lock()
+	cached, ok := c.items[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.page, true, cached.err
+	}
+
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		// the shared request must not be canceled by the first client
+		return c.store(key, url, accept)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, false, fmt.Errorf("failed to request upstream: %w", ctx.Err())
+	case res := <-result:
+		page, _ := res.Val.(*Page)
+
+		return page, false, res.Err
+	}
+}
+
+func (c *Cache) store(key, url, accept string) (*Page, error) {
+	now := time.Now()
+	page, err := c.fetch(context.Background(), url, accept)
+
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.failureTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPurge) > max(c.ttl, c.failureTTL) {
+		for k, item := range c.items {
+			if now.After(item.expires) {
+				delete(c.items, k)
+			}
+		}
+
+		c.lastPurge = now
+	}
+
+	if ttl > 0 {
+		c.items[key] = &item{
+			page:    page,
+			err:     err,
+			expires: now.Add(ttl),
+		}
+	}
+
+	return page, err
+}
+
+func (c *Cache) fetch(ctx context.Context, url, accept string) (*Page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upstream request: %w", err)
+	}
+
+	req.Header.Set("Accept", accept)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request upstream: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", ErrStatus, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upstream page: %w", err)
+	}
+
+	if int64(len(body)) > c.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrSize, c.maxSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	return &Page{
+		ContentType: contentType,
+		Body:        body,
+	}, nil
+}
@@ -0,0 +1,234 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stub(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		handler(writer, req)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, calls
+}
+
+func page(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "text/html")
+	_, _ = writer.Write([]byte("<html>upstream</html>"))
+}
+
+func TestFetchCached(t *testing.T) {
+	server, calls := stub(t, page)
+	cache := NewCache(time.Second, time.Minute, time.Minute, 1024)
+
+	result, cached, err := cache.Fetch(context.Background(), server.URL, "text/html")
+	if err != nil || cached {
+		t.Fatalf("first fetch: cached %v, error %v", cached, err)
+	}
+
+	if string(result.Body) != "<html>upstream</html>" || result.ContentType != "text/html" {
+		t.Fatalf("unexpected page %q with type %q", result.Body, result.ContentType)
+	}
+
+	result, cached, err = cache.Fetch(context.Background(), server.URL, "text/html")
+	if err != nil || !cached || string(result.Body) != "<html>upstream</html>" {
+		t.Fatalf("second fetch: cached %v, error %v", cached, err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 upstream request, got %d", calls.Load())
+	}
+
+	// pages are cached per accepted content type
+	if _, cached, _ := cache.Fetch(context.Background(), server.URL, "application/json"); cached {
+		t.Fatal("expected other content type to miss the cache")
+	}
+}
+
+func TestFetchExpired(t *testing.T) {
+	server, calls := stub(t, page)
+	cache := NewCache(time.Second, 50*time.Millisecond, time.Minute, 1024)
+
+	if _, _, err := cache.Fetch(context.Background(), server.URL, "text/html"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, cached, err := cache.Fetch(context.Background(), server.URL, "text/html")
+	if err != nil || cached {
+		t.Fatalf("expected refetch after TTL: cached %v, error %v", cached, err)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", calls.Load())
+	}
+}
+
+func TestFetchFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		handler http.HandlerFunc
+		err     error
+	}{
+		{
+			name:    "status",
+			timeout: time.Second,
+			handler: func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusServiceUnavailable)
+			},
+			err: ErrStatus,
+		},
+		{
+			name:    "size",
+			timeout: time.Second,
+			handler: func(writer http.ResponseWriter, _ *http.Request) {
+				_, _ = writer.Write(make([]byte, 2048))
+			},
+			err: ErrSize,
+		},
+		{
+			name:    "timeout",
+			timeout: 50 * time.Millisecond,
+			handler: func(_ http.ResponseWriter, req *http.Request) {
+				select {
+				case <-req.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := stub(t, tt.handler)
+			cache := NewCache(tt.timeout, time.Minute, time.Minute, 1024)
+
+			result, cached, err := cache.Fetch(context.Background(), server.URL, "text/html")
+			if err == nil || result != nil || cached {
+				t.Fatalf("expected failure: page %v, cached %v, error %v", result, cached, err)
+			}
+
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			// the failure TTL prevents requesting the broken upstream again
+			_, cached, err = cache.Fetch(context.Background(), server.URL, "text/html")
+			if err == nil || !cached {
+				t.Fatalf("expected cached failure: cached %v, error %v", cached, err)
+			}
+
+			if calls.Load() != 1 {
+				t.Fatalf("expected 1 upstream request, got %d", calls.Load())
+			}
+		})
+	}
+}
+
+func TestFetchFailureExpired(t *testing.T) {
+	failing := atomic.Bool{}
+	failing.Store(true)
+
+	server, calls := stub(t, func(writer http.ResponseWriter, req *http.Request) {
+		if failing.Load() {
+			writer.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		page(writer, req)
+	})
+
+	cache := NewCache(time.Second, time.Minute, 50*time.Millisecond, 1024)
+
+	if _, _, err := cache.Fetch(context.Background(), server.URL, "text/html"); err == nil {
+		t.Fatal("expected failure")
+	}
+
+	failing.Store(false)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, cached, err := cache.Fetch(context.Background(), server.URL, "text/html"); err != nil || cached {
+		t.Fatalf("expected recovery after failure TTL: cached %v, error %v", cached, err)
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", calls.Load())
+	}
+}
+
+func TestFetchShared(t *testing.T) {
+	release := make(chan struct{})
+
+	server, calls := stub(t, func(writer http.ResponseWriter, req *http.Request) {
+		<-release
+		page(writer, req)
+	})
+
+	cache := NewCache(time.Second, time.Minute, time.Minute, 1024)
+	wg := sync.WaitGroup{}
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, _, err := cache.Fetch(context.Background(), server.URL, "text/html"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 shared upstream request, got %d", calls.Load())
+	}
+}
+
+func TestFetchCanceled(t *testing.T) {
+	release := make(chan struct{})
+
+	server, calls := stub(t, func(writer http.ResponseWriter, req *http.Request) {
+		<-release
+		page(writer, req)
+	})
+
+	cache := NewCache(time.Second, time.Minute, time.Minute, 1024)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	if _, _, err := cache.Fetch(ctx, server.URL, "text/html"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled fetch, got %v", err)
+	}
+
+	close(release)
+
+	// the canceled client doesn't abort the shared request
+	if _, _, err := cache.Fetch(context.Background(), server.URL, "text/html"); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 upstream request, got %d", calls.Load())
+	}
+}
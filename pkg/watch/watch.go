@@ -0,0 +1,94 @@
+// Package watch triggers reloads whenever watched files change on disk.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// Delay defines the delay to collect related file events before a reload.
+const Delay = 250 * time.Millisecond
+
+// Watch calls reload on changes of the paths until the context gets canceled.
+//
+// Directories get watched directly while files are watched through their
+// parent directories, that way atomic replacements like the symlink swaps of
+// Kubernetes secrets and config maps are detected.
+func Watch(ctx context.Context, paths []string, reload func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	defer watcher.Close()
+
+	for _, dir := range watchDirs(paths) {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	timer := time.NewTimer(Delay)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+
+			timer.Reset(Delay)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.Warn().
+				Err(err).
+				Strs("paths", paths).
+				Msg("Failed to watch files")
+
+		case <-timer.C:
+			reload()
+		}
+	}
+}
+
+func watchDirs(paths []string) []string {
+	result := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		dir := path
+
+		if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+			dir = filepath.Dir(path)
+		}
+
+		if seen[dir] {
+			continue
+		}
+
+		seen[dir] = true
+		result = append(result, dir)
+	}
+
+	return result
+}